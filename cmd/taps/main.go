@@ -1,18 +1,14 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/meszmate/taps/internal/app"
+	"github.com/meszmate/taps/internal/cli"
 )
 
+// version is set at build time by goreleaser
+var version = "dev"
+
 func main() {
-	m := app.New()
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	os.Exit(cli.Run(os.Args[1:], version, os.Stdout, os.Stderr))
 }
@@ -1,17 +1,24 @@
 package app
 
 import (
+	"fmt"
+	"slices"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/meszmate/taps/internal/config"
 	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/race"
+	"github.com/meszmate/taps/internal/typing"
 	"github.com/meszmate/taps/internal/ui/menu"
 	"github.com/meszmate/taps/internal/ui/results"
 	"github.com/meszmate/taps/internal/ui/settings"
 	"github.com/meszmate/taps/internal/ui/styles"
 	"github.com/meszmate/taps/internal/ui/test"
 	"github.com/meszmate/taps/internal/ui/theme"
+	"github.com/meszmate/taps/internal/ui/themeeditor"
 
 	historyui "github.com/meszmate/taps/internal/ui/history"
+	raceui "github.com/meszmate/taps/internal/ui/race"
 )
 
 type screen int
@@ -22,6 +29,8 @@ const (
 	screenResults
 	screenSettings
 	screenHistory
+	screenThemeEditor
+	screenRace
 )
 
 type Model struct {
@@ -34,12 +43,23 @@ type Model struct {
 	results    results.Model
 	settings   settings.Model
 	history    historyui.Model
+	editor     themeeditor.Model
+	race       raceui.Model
 	windowSize tea.WindowSizeMsg
 }
 
-func New() Model {
-	cfg := config.Load()
-	t := theme.GetTheme(cfg.Theme)
+// Options control how the app starts
+type Options struct {
+	// StartTest skips the menu and starts a test with the configured mode
+	StartTest bool
+	// RaceClient opens the race joined on it instead of the menu, hosted
+	// by RaceServer when this instance is the host
+	RaceClient *race.Client
+	RaceServer *race.Server
+}
+
+func New(cfg *config.Config, opts Options) Model {
+	t := resolveTheme(cfg)
 	s := styles.New(t)
 
 	m := Model{
@@ -49,10 +69,32 @@ func New() Model {
 		theme:  t,
 		menu:   menu.New(cfg, s),
 	}
+	if opts.StartTest {
+		m.test = m.newTest(cfg.Mode, cfg.Duration, cfg.WordCount, cfg.QuoteLength)
+		m.screen = screenTest
+	}
+	if opts.RaceClient != nil {
+		m.race = raceui.New(cfg, s, opts.RaceClient, opts.RaceServer)
+		m.screen = screenRace
+	}
 	return m
 }
 
+// resolveTheme returns the theme selected in cfg, using the user's custom
+// colors when the custom theme is selected
+func resolveTheme(cfg *config.Config) *theme.Theme {
+	if cfg.Theme == theme.CustomName && cfg.CustomTheme != nil {
+		ct := cfg.CustomTheme
+		return theme.CustomTheme(ct.Name, ct.Background, ct.Foreground, ct.Sub, ct.Main,
+			ct.Caret, ct.Correct, ct.Error, ct.ExtraError)
+	}
+	return theme.GetTheme(cfg.Theme)
+}
+
 func (m Model) Init() tea.Cmd {
+	if m.screen == screenRace {
+		return m.race.Init()
+	}
 	return nil
 }
 
@@ -77,6 +119,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateSettings(msg)
 	case screenHistory:
 		return m.updateHistory(msg)
+	case screenThemeEditor:
+		return m.updateThemeEditor(msg)
+	case screenRace:
+		return m.updateRace(msg)
 	}
 	return m, nil
 }
@@ -91,12 +137,16 @@ func (m Model) updateMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.menu, cmd = m.menu.Update(msg)
 
+	switch msg.(type) {
+	case menu.StartTestMsg, menu.OpenSettingsMsg, menu.OpenHistoryMsg:
+		// Config warnings have been seen on the menu by now
+		m.config.DismissWarnings()
+	}
+
 	switch msg.(type) {
 	case menu.StartTestMsg:
 		stMsg := msg.(menu.StartTestMsg)
-		m.test = test.New(m.config, m.styles, stMsg.Mode, stMsg.Duration, stMsg.WordCount, stMsg.QuoteLength)
-		m.test.Width = m.windowSize.Width
-		m.test.Height = m.windowSize.Height
+		m.test = m.newTest(stMsg.Mode, stMsg.Duration, stMsg.WordCount, stMsg.QuoteLength)
 		m.screen = screenTest
 		return m, nil
 	case menu.OpenSettingsMsg:
@@ -112,30 +162,59 @@ func (m Model) updateMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// newTest sets up the test screen, with the configured pace caret
+func (m Model) newTest(mode string, duration, wordCount int, quoteLength string) test.Model {
+	t := test.New(m.config, m.styles, mode, duration, wordCount, quoteLength)
+	t.Engine.Ghost = paceGhost(m.config, t)
+	t.Width = m.windowSize.Width
+	t.Height = m.windowSize.Height
+	return t
+}
+
+// paceGhost returns the pace caret selected in the config for test t, or
+// nil when it is off or there is no earlier result with t's config to pace
+// it on
+func paceGhost(cfg *config.Config, t test.Model) *typing.Ghost {
+	if cfg.PaceCaret == "off" || t.Mode == "zen" {
+		return nil
+	}
+	if cfg.PaceCaret == "custom" {
+		return &typing.Ghost{Label: fmt.Sprintf("%d wpm", cfg.PaceCaretWPM), WPM: float64(cfg.PaceCaretWPM)}
+	}
+
+	key := testResult(t.Engine, t.TCfg).ConfigKey()
+	stats, err := history.Default().Stats(key.Filter())
+	if err != nil || stats.PersonalBest == nil {
+		return nil
+	}
+	pb := stats.PersonalBest
+	switch cfg.PaceCaret {
+	case "average":
+		return &typing.Ghost{Label: "average", WPM: stats.AverageWPM}
+	case "replay":
+		// results saved before keystrokes were logged are paced steadily
+		return &typing.Ghost{Label: "pb replay", WPM: pb.NetWPM, Replay: pb.Replay()}
+	}
+	return &typing.Ghost{Label: "pb", WPM: pb.NetWPM}
+}
+
 func (m Model) updateTest(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.test, cmd = m.test.Update(msg)
 
 	switch msg := msg.(type) {
 	case test.TestFinishedMsg:
-		result := history.TestResult{
-			Date:        msg.Engine.StartTime,
-			Mode:        msg.Config.Mode,
-			Duration:    msg.Config.Duration,
-			WordCount:   msg.Config.WordCount,
-			Language:    msg.Config.Language,
-			Punctuation: msg.Config.Punctuation,
-			Numbers:     msg.Config.Numbers,
-			Difficulty:  msg.Config.Difficulty,
-			NetWPM:      msg.Engine.CurrentNetWPM(),
-			RawWPM:      msg.Engine.CurrentRawWPM(),
-			Accuracy:    msg.Engine.CurrentAccuracy(),
-			Consistency: msg.Engine.CurrentConsistency(),
-			Correct:     msg.Engine.CorrectChars,
-			Incorrect:   msg.Engine.IncorrectChars,
-			Extra:       msg.Engine.ExtraChars,
-			Missed:      msg.Engine.MissedChars,
-			QuoteLength: msg.Config.QuoteLength,
+		result := finishedResult(msg.Engine, msg.Config)
+		// Look up the best result before this one is added, to tell whether
+		// it set a new personal best
+		prev, _ := history.Default().Stats(result.ConfigKey().Filter())
+		if result.Completed() && (prev.PersonalBest == nil || result.NetWPM > prev.BestWPM) {
+			// only personal bests are replayed, so only they keep their
+			// keystrokes
+			result.Keystrokes = history.KeystrokeLog(msg.Engine.Keystrokes)
+			if prev.PersonalBest != nil {
+				_ = history.ClearKeystrokes(prev.PersonalBest.Date)
+			}
 		}
 		_ = history.Append(result)
 
@@ -150,12 +229,20 @@ func (m Model) updateTest(msg tea.Msg) (tea.Model, tea.Cmd) {
 			QuoteLength: msg.Config.QuoteLength,
 		}
 		m.results = results.New(m.styles, msg.Engine, msg.Mode, tcfg)
+		m.results.PreviousBest = prev.PersonalBest
 		m.results.Width = m.windowSize.Width
 		m.results.Height = m.windowSize.Height
 		m.screen = screenResults
 		return m, nil
 
 	case test.BackToMenuMsg:
+		// A test left after it started is kept as aborted, so it counts
+		// towards practice time but not towards speed stats
+		if e := m.test.Engine; e.Started && !e.Finished && !e.Failed {
+			result := testResult(e, m.test.TCfg)
+			result.Outcome = history.OutcomeAborted
+			_ = history.Append(result)
+		}
 		m.menu = menu.New(m.config, m.styles)
 		m.screen = screenMenu
 		return m, m.sendSize()
@@ -164,15 +251,55 @@ func (m Model) updateTest(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// finishedResult builds the history record of a test that ran to its end
+// or failed
+func finishedResult(e *typing.Engine, cfg test.TestConfig) history.TestResult {
+	result := testResult(e, cfg)
+	result.Outcome = history.OutcomeCompleted
+	if e.Failed {
+		result.Outcome = history.OutcomeFailed
+		result.FailReason = e.FailedReason
+	} else if e.AFK {
+		result.Outcome = history.OutcomeAFK
+	}
+	return result
+}
+
+// testResult builds the history record of a test from its engine
+func testResult(e *typing.Engine, cfg test.TestConfig) history.TestResult {
+	return history.TestResult{
+		Date:           e.StartTime,
+		Mode:           cfg.Mode,
+		Duration:       cfg.Duration,
+		WordCount:      cfg.WordCount,
+		Language:       cfg.Language,
+		Punctuation:    cfg.Punctuation,
+		Numbers:        cfg.Numbers,
+		Difficulty:     cfg.Difficulty,
+		NetWPM:         e.CurrentNetWPM(),
+		RawWPM:         e.CurrentRawWPM(),
+		Accuracy:       e.CurrentAccuracy(),
+		Consistency:    e.CurrentConsistency(),
+		Correct:        e.CorrectChars,
+		Incorrect:      e.IncorrectChars,
+		Extra:          e.ExtraChars,
+		Missed:         e.MissedChars,
+		QuoteLength:    cfg.QuoteLength,
+		PerSecondWPM:   slices.Clone(e.PerSecondWPM),
+		ElapsedSeconds: e.ElapsedSeconds(),
+		AFKSeconds:     e.IdleSeconds,
+		PausedSeconds:  e.PausedSeconds,
+		MetricsProfile: e.MetricsProfile,
+	}
+}
+
 func (m Model) updateResults(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.results, cmd = m.results.Update(msg)
 
 	switch msg := msg.(type) {
 	case results.RestartMsg:
-		m.test = test.New(m.config, m.styles, msg.Mode, msg.Duration, msg.WordCount, msg.QuoteLength)
-		m.test.Width = m.windowSize.Width
-		m.test.Height = m.windowSize.Height
+		m.test = m.newTest(msg.Mode, msg.Duration, msg.WordCount, msg.QuoteLength)
 		m.screen = screenTest
 		return m, nil
 	case results.NewTestMsg:
@@ -192,16 +319,61 @@ func (m Model) updateSettings(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.settings, cmd = m.settings.Update(msg)
 
-	switch msg := msg.(type) {
+	switch msg.(type) {
 	case settings.BackToMenuMsg:
+		if m.config.HistoryBackend != history.Backend() {
+			if err := history.Use(m.config.HistoryBackend); err != nil {
+				// the results could not be moved, so keep the previous backend
+				m.config.HistoryBackend = history.Backend()
+				_ = m.config.Save()
+			}
+		}
 		m.menu = menu.New(m.config, m.styles)
 		m.screen = screenMenu
 		return m, m.sendSize()
 	case settings.ThemeChangedMsg:
-		m.theme = theme.GetTheme(msg.ThemeName)
+		m.theme = resolveTheme(m.config)
 		m.styles = styles.New(m.theme)
 		m.settings.Styles = m.styles
 		return m, nil
+	case settings.OpenThemeEditorMsg:
+		m.editor = themeeditor.New(m.styles, m.theme)
+		m.screen = screenThemeEditor
+		return m, m.sendSize()
+	}
+
+	return m, cmd
+}
+
+func (m Model) updateThemeEditor(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+
+	switch msg := msg.(type) {
+	case themeeditor.SavedMsg:
+		t := msg.Theme
+		m.config.CustomTheme = &config.CustomThemeConfig{
+			Name:       t.Name,
+			Background: string(t.Background),
+			Foreground: string(t.Foreground),
+			Sub:        string(t.Sub),
+			Main:       string(t.Main),
+			Caret:      string(t.Caret),
+			Correct:    string(t.Correct),
+			Error:      string(t.Error),
+			ExtraError: string(t.ExtraError),
+		}
+		m.config.Theme = theme.CustomName
+		_ = m.config.Save()
+		m.theme = resolveTheme(m.config)
+		m.styles = styles.New(m.theme)
+		m.settings = settings.New(m.config, m.styles)
+		m.screen = screenSettings
+		return m, m.sendSize()
+	case themeeditor.BackMsg:
+		m.settings = settings.New(m.config, m.styles)
+		m.screen = screenSettings
+		return m, m.sendSize()
 	}
 
 	return m, cmd
@@ -221,6 +393,31 @@ func (m Model) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m Model) updateRace(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.race, cmd = m.race.Update(msg)
+
+	switch msg := msg.(type) {
+	case test.TestFinishedMsg:
+		// races count as words tests of their text, but not as personal bests
+		result := finishedResult(msg.Engine, msg.Config)
+		result.Source = history.SourceRace
+		_ = history.Append(result)
+	case raceui.LeaveMsg:
+		// leaving mid-race is kept as aborted, like leaving a test
+		t := m.race.Test()
+		if e := t.Engine; e != nil && e.Started && !e.Finished && !e.Failed {
+			result := testResult(e, t.TCfg)
+			result.Outcome = history.OutcomeAborted
+			result.Source = history.SourceRace
+			_ = history.Append(result)
+		}
+		return m, tea.Quit
+	}
+
+	return m, cmd
+}
+
 func (m Model) View() string {
 	switch m.screen {
 	case screenMenu:
@@ -233,6 +430,10 @@ func (m Model) View() string {
 		return m.settings.View()
 	case screenHistory:
 		return m.history.View()
+	case screenThemeEditor:
+		return m.editor.View()
+	case screenRace:
+		return m.race.View()
 	}
 	return ""
 }
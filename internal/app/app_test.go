@@ -0,0 +1,94 @@
+package app
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/race"
+)
+
+// waitFor polls cond until it holds or fails the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRaceSavedOnce(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	srv, err := race.Listen("127.0.0.1:0", race.Race{WordCount: 1, Language: "english", Text: "ab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	defer srv.Close()
+	player, err := race.Join(srv.Addr().String(), "player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer player.Close()
+	// a second racer who never finishes keeps the race going
+	other, err := race.Join(srv.Addr().String(), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	p := tea.NewProgram(New(config.DefaultConfig(), Options{RaceClient: player, RaceServer: srv}),
+		tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer())
+	done := make(chan struct{})
+	go func() {
+		p.Run()
+		close(done)
+	}()
+	defer func() {
+		p.Quit()
+		<-done
+	}()
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// the player reports progress once the countdown is over
+	for {
+		m, err := other.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Type == race.TypeUpdate {
+			break
+		}
+	}
+
+	saved := func() int {
+		results, _ := history.Load()
+		return len(results)
+	}
+	for _, r := range "ab" {
+		p.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	waitFor(t, "the race result", func() bool { return saved() == 1 })
+
+	// keys pressed while waiting for the other racer
+	for _, r := range "xyz" {
+		p.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := saved(); n != 1 {
+		t.Errorf("saved %d results, want 1", n)
+	}
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/meszmate/taps/internal/app"
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/typing"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+func commands() []command {
+	return []command{
+		{"stats", "print aggregate statistics from your history", runStats},
+		{"history", "print recent test results", runHistory},
+		{"export", "write your history to a file or stdout", runExport},
+		{"import", "add results from Monkeytype or another tool's CSV", runImport},
+		{"race", "race others on your network: race host, race join <host:port>", runRace},
+		{"themes", "list available themes", runThemes},
+		{"languages", "list available word lists", runLanguages},
+		{"version", "print the taps version", nil},
+		{"help", "show this help", nil},
+	}
+}
+
+// usageError marks errors caused by invalid arguments
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// Run parses args and runs the matching subcommand, or the interactive app
+// when none is given. It returns the process exit code.
+func Run(args []string, version string, stdout, stderr io.Writer) int {
+	defer history.Close()
+
+	var err error
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		err = runCommand(args[0], args[1:], version, stdout, stderr)
+	} else {
+		err = runApp(args, version, stdout, stderr)
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, new(usageError)):
+		fmt.Fprintf(stderr, "taps: %v\n", err)
+		fmt.Fprintln(stderr, "Run 'taps help' for usage.")
+		return 2
+	default:
+		fmt.Fprintf(stderr, "taps: %v\n", err)
+		return 1
+	}
+}
+
+func runCommand(name string, args []string, version string, stdout, stderr io.Writer) error {
+	switch name {
+	case "help":
+		printUsage(stdout, newAppFlags(io.Discard).fs)
+		return nil
+	case "version":
+		fmt.Fprintf(stdout, "taps %s\n", version)
+		return nil
+	}
+	for _, c := range commands() {
+		if c.name == name {
+			return c.run(args, stdout, stderr)
+		}
+	}
+	return usagef("unknown command %q", name)
+}
+
+// appFlags are the flags accepted when starting the interactive app
+type appFlags struct {
+	fs          *flag.FlagSet
+	mode        string
+	duration    int
+	wordCount   int
+	quote       string
+	language    string
+	punctuation bool
+	numbers     bool
+	theme       string
+	seed        int64
+	version     bool
+}
+
+func newAppFlags(output io.Writer) *appFlags {
+	f := &appFlags{fs: flag.NewFlagSet("taps", flag.ContinueOnError)}
+	f.fs.SetOutput(output)
+	f.fs.StringVar(&f.mode, "mode", "", "start a test in `mode` (time, words, quote, zen)")
+	f.fs.IntVar(&f.duration, "time", 0, "start a time test lasting `seconds`")
+	f.fs.IntVar(&f.wordCount, "words", 0, "start a words test with `count` words")
+	f.fs.StringVar(&f.quote, "quote", "", "start a quote test with quotes of `length` (short, medium, long)")
+	f.fs.StringVar(&f.language, "language", "", "word list to use (see 'taps languages')")
+	f.fs.BoolVar(&f.punctuation, "punctuation", false, "add punctuation to generated words")
+	f.fs.BoolVar(&f.numbers, "numbers", false, "add numbers to generated words")
+	f.fs.StringVar(&f.theme, "theme", "", "theme to use (see 'taps themes')")
+	f.fs.Int64Var(&f.seed, "seed", 0, "seed word generation so the test text is reproducible")
+	f.fs.BoolVar(&f.version, "version", false, "print the taps version and exit")
+	return f
+}
+
+func runApp(args []string, version string, stdout, stderr io.Writer) error {
+	f := newAppFlags(stderr)
+	f.fs.Usage = func() { printUsage(stderr, f.fs) }
+	if err := f.fs.Parse(args); err != nil {
+		return err
+	}
+	if f.fs.NArg() > 0 {
+		return usagef("unexpected argument %q", f.fs.Arg(0))
+	}
+	if f.version {
+		fmt.Fprintf(stdout, "taps %s\n", version)
+		return nil
+	}
+
+	set := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	cfg := config.Load()
+	if err := f.validate(set, cfg); err != nil {
+		return err
+	}
+	if err := history.Use(cfg.HistoryBackend); err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	cfg.Override(func(c *config.Config) { f.apply(set, c) })
+	if set["seed"] {
+		typing.Seed(f.seed)
+	}
+
+	start := set["mode"] || set["time"] || set["words"] || set["quote"]
+	p := tea.NewProgram(app.New(cfg, app.Options{StartTest: start}), tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
+
+func (f *appFlags) validate(set map[string]bool, cfg *config.Config) error {
+	var selectors []string
+	for _, name := range []string{"time", "words", "quote"} {
+		if set[name] {
+			selectors = append(selectors, "--"+name)
+		}
+	}
+	if len(selectors) > 1 {
+		return usagef("%s cannot be combined", strings.Join(selectors, " and "))
+	}
+	if set["mode"] {
+		if !slices.Contains(config.Modes, f.mode) {
+			return usagef("invalid mode %q (want one of %s)", f.mode, strings.Join(config.Modes, ", "))
+		}
+		if len(selectors) == 1 && selectors[0] != "--"+f.mode {
+			return usagef("--mode %s conflicts with %s", f.mode, selectors[0])
+		}
+	}
+	if set["time"] && f.duration <= 0 {
+		return usagef("--time must be a positive number of seconds")
+	}
+	if set["words"] && f.wordCount <= 0 {
+		return usagef("--words must be a positive word count")
+	}
+	if set["quote"] && !slices.Contains(config.QuoteLengths, f.quote) {
+		return usagef("invalid quote length %q (want one of %s)", f.quote, strings.Join(config.QuoteLengths, ", "))
+	}
+	if set["language"] && !slices.Contains(typing.Languages(), f.language) {
+		return usagef("unknown language %q (run 'taps languages' to list them)", f.language)
+	}
+	if set["theme"] {
+		known := slices.Contains(theme.ThemeNames(), f.theme)
+		if f.theme == theme.CustomName {
+			known = cfg.CustomTheme != nil
+		}
+		if !known {
+			return usagef("unknown theme %q (run 'taps themes' to list them)", f.theme)
+		}
+	}
+	return nil
+}
+
+func (f *appFlags) apply(set map[string]bool, c *config.Config) {
+	if set["mode"] {
+		c.Mode = f.mode
+	}
+	if set["time"] {
+		c.Mode = "time"
+		c.Duration = f.duration
+	}
+	if set["words"] {
+		c.Mode = "words"
+		c.WordCount = f.wordCount
+	}
+	if set["quote"] {
+		c.Mode = "quote"
+		c.QuoteLength = f.quote
+	}
+	if set["language"] {
+		c.Language = f.language
+	}
+	if set["punctuation"] {
+		c.Punctuation = f.punctuation
+	}
+	if set["numbers"] {
+		c.Numbers = f.numbers
+	}
+	if set["theme"] {
+		c.Theme = f.theme
+	}
+}
+
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  taps [flags]              open the menu, or start a test directly")
+	fmt.Fprintln(w, "  taps <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags override your saved settings for this session only. Passing")
+	fmt.Fprintln(w, "--mode, --time, --words or --quote skips the menu and starts the test.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'taps <command> -h' for command flags.")
+}
@@ -0,0 +1,565 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/typing"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("taps "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+// filterFlags registers the result filter flags shared by history commands
+type filterFlags struct {
+	mode       string
+	language   string
+	difficulty string
+	metrics    string
+	outcome    string
+	paused     string
+	since      string
+	until      string
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filterFlags {
+	f := &filterFlags{}
+	fs.StringVar(&f.mode, "mode", "", "only include tests in `mode` (time, words, quote, zen)")
+	fs.StringVar(&f.language, "language", "", "only include tests using word list `name`")
+	fs.StringVar(&f.difficulty, "difficulty", "", "only include tests at `level` (normal, expert, master)")
+	fs.StringVar(&f.metrics, "metrics", "", "only include tests scored with metrics `profile` (taps, monkeytype)")
+	fs.StringVar(&f.outcome, "outcome", "", "only include tests that ended as `outcome` (completed, failed, aborted, afk)")
+	fs.StringVar(&f.paused, "paused", "", "only include tests that were (yes) or were not (no) paused")
+	fs.StringVar(&f.since, "since", "", "only include tests on or after `date` (YYYY-MM-DD or e.g. 7d)")
+	fs.StringVar(&f.until, "until", "", "only include tests on or before `date` (YYYY-MM-DD or e.g. 7d)")
+	return f
+}
+
+func (f *filterFlags) filter(now time.Time) (history.Filter, error) {
+	hf := history.Filter{
+		Mode:           f.mode,
+		Language:       f.language,
+		Difficulty:     f.difficulty,
+		MetricsProfile: f.metrics,
+		Outcome:        f.outcome,
+	}
+	if f.mode != "" && !slices.Contains(config.Modes, f.mode) {
+		return hf, usagef("invalid mode %q (want one of %s)", f.mode, strings.Join(config.Modes, ", "))
+	}
+	if f.difficulty != "" && !slices.Contains(config.Difficulties, f.difficulty) {
+		return hf, usagef("invalid difficulty %q (want one of %s)", f.difficulty, strings.Join(config.Difficulties, ", "))
+	}
+	if f.metrics != "" && !slices.Contains(typing.Profiles, f.metrics) {
+		return hf, usagef("invalid metrics profile %q (want one of %s)", f.metrics, strings.Join(typing.Profiles, ", "))
+	}
+	if f.outcome != "" && !slices.Contains(history.Outcomes, f.outcome) {
+		return hf, usagef("invalid outcome %q (want one of %s)", f.outcome, strings.Join(history.Outcomes, ", "))
+	}
+	switch f.paused {
+	case "":
+	case "yes", "no":
+		paused := f.paused == "yes"
+		hf.Paused = &paused
+	default:
+		return hf, usagef("invalid paused %q (want yes or no)", f.paused)
+	}
+	if f.since != "" {
+		t, err := parseDay(f.since, now)
+		if err != nil {
+			return hf, usagef("invalid --since: %v", err)
+		}
+		hf.Since = t
+	}
+	if f.until != "" {
+		t, err := parseDay(f.until, now)
+		if err != nil {
+			return hf, usagef("invalid --until: %v", err)
+		}
+		hf.Until = t.AddDate(0, 0, 1)
+	}
+	return hf, nil
+}
+
+// parseDay parses an absolute YYYY-MM-DD date or a relative number of days
+// ago such as "7d", returning the start of that day in local time
+func parseDay(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("%q is not a number of days", s)
+		}
+		y, m, d := now.AddDate(0, 0, -n).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD date", s)
+	}
+	return t, nil
+}
+
+// openHistory selects the history backend configured in the settings
+func openHistory(stderr io.Writer) (history.Store, error) {
+	cfg := loadConfig(stderr)
+	if err := history.Use(cfg.HistoryBackend); err != nil {
+		return nil, fmt.Errorf("opening history: %w", err)
+	}
+	return history.Default(), nil
+}
+
+// openFiltered opens the history and parses the filter flags
+func openFiltered(f *filterFlags, stderr io.Writer) (history.Store, history.Filter, error) {
+	hf, err := f.filter(time.Now())
+	if err != nil {
+		return nil, hf, err
+	}
+	store, err := openHistory(stderr)
+	return store, hf, err
+}
+
+// loadFiltered reads the results matching the filter flags
+func loadFiltered(f *filterFlags, stderr io.Writer) ([]history.TestResult, error) {
+	store, hf, err := openFiltered(f, stderr)
+	if err != nil {
+		return nil, err
+	}
+	results, err := store.Query(hf)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return results, nil
+}
+
+// newestFirst returns results sorted by date, most recent first, keeping at
+// most limit entries when limit is positive
+func newestFirst(results []history.TestResult, limit int) []history.TestResult {
+	sorted := make([]history.TestResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.After(sorted[j].Date)
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runStats(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("stats", stderr)
+	filters := addFilterFlags(fs)
+	recent := fs.Int("recent", 5, "include the `n` most recent results")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	store, hf, err := openFiltered(filters, stderr)
+	if err != nil {
+		return err
+	}
+	s, err := store.Stats(hf)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	pbs, err := store.PersonalBests(hf)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	results, err := store.Query(hf)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	var latest []history.TestResult
+	if *recent > 0 {
+		latest = newestFirst(results, *recent)
+	}
+	days := history.Activity(results)
+	streak, longest := history.Streak(days, time.Now())
+
+	if *asJSON {
+		out := struct {
+			history.Stats
+			DaysPracticed int                  `json:"days_practiced"`
+			Streak        int                  `json:"streak"`
+			LongestStreak int                  `json:"longest_streak"`
+			PersonalBests []history.TestResult `json:"personal_bests"`
+			Recent        []history.TestResult `json:"recent"`
+		}{s, len(days), streak, longest, nonNil(pbs), nonNil(latest)}
+		return writeJSON(stdout, out)
+	}
+
+	typed := time.Duration(s.TotalSeconds * float64(time.Second))
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "tests\t%d (%d completed)\n", s.TotalTests, s.Completed)
+	fmt.Fprintf(tw, "failed\t%d (%.1f%%)\n", s.Failed, s.Rate(s.Failed))
+	fmt.Fprintf(tw, "aborted\t%d (%.1f%%)\n", s.Aborted, s.Rate(s.Aborted))
+	fmt.Fprintf(tw, "afk\t%d (%.1f%%)\n", s.AFK, s.Rate(s.AFK))
+	fmt.Fprintf(tw, "average wpm\t%.1f\n", s.AverageWPM)
+	fmt.Fprintf(tw, "median wpm\t%.1f\n", s.MedianWPM)
+	fmt.Fprintf(tw, "percentiles\tp10 %.1f  p25 %.1f  p75 %.1f  p90 %.1f\n",
+		s.Percentiles.P10, s.Percentiles.P25, s.Percentiles.P75, s.Percentiles.P90)
+	fmt.Fprintf(tw, "std dev\t%.1f\n", s.StdDevWPM)
+	fmt.Fprintf(tw, "last 10 avg\t%.1f\n", s.Last10Avg)
+	if s.PersonalBest != nil {
+		fmt.Fprintf(tw, "best wpm\t%.1f (%s, %s)\n", s.BestWPM,
+			describeConfig(*s.PersonalBest), s.PersonalBest.Date.Format("2006-01-02"))
+	}
+	fmt.Fprintf(tw, "accuracy\t%.1f%%\n", s.AverageAccuracy)
+	fmt.Fprintf(tw, "consistency\t%.1f%%\n", s.AverageConsistency)
+	if s.ImprovementPerWeek != 0 {
+		fmt.Fprintf(tw, "trend\t%+.2f wpm per week\n", s.ImprovementPerWeek)
+	}
+	fmt.Fprintf(tw, "words typed\t%d\n", s.TotalWords)
+	fmt.Fprintf(tw, "time typed\t%dh %02dm\n", int(typed.Hours()), int(typed.Minutes())%60)
+	fmt.Fprintf(tw, "days practiced\t%d\n", len(days))
+	fmt.Fprintf(tw, "streak\t%d (longest %d)\n", streak, longest)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, breakdown := range []struct {
+		title  string
+		groups []history.GroupStats
+	}{{"by mode", s.ByMode}, {"by language", s.ByLanguage}} {
+		if len(breakdown.groups) < 2 {
+			continue
+		}
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, breakdown.title)
+		if err := writeGroupTable(stdout, breakdown.groups); err != nil {
+			return err
+		}
+	}
+
+	if len(pbs) > 0 {
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, "personal bests")
+		if err := writeResultTable(stdout, pbs); err != nil {
+			return err
+		}
+	}
+	if len(latest) > 0 {
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, "recent")
+		if err := writeResultTable(stdout, latest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runHistory(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("history", stderr)
+	filters := addFilterFlags(fs)
+	limit := fs.Int("limit", 20, "show at most `n` results (0 for all)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	results, err := loadFiltered(filters, stderr)
+	if err != nil {
+		return err
+	}
+	results = newestFirst(results, *limit)
+
+	if *asJSON {
+		return writeJSON(stdout, nonNil(results))
+	}
+	return writeResultTable(stdout, results)
+}
+
+func writeResultTable(w io.Writer, results []history.TestResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tWPM\tRAW\tACC\tCONSIST\tCONFIG")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f%%\t%.1f%%\t%s\n",
+			r.Date.Format("2006-01-02 15:04"), r.NetWPM, r.RawWPM, r.Accuracy, r.Consistency, describeConfig(r))
+	}
+	return tw.Flush()
+}
+
+func writeGroupTable(w io.Writer, groups []history.GroupStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTESTS\tAVG WPM\tBEST WPM\tACC")
+	for _, g := range groups {
+		name := g.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f\t%.1f%%\n", name, g.Tests, g.AverageWPM, g.BestWPM, g.AverageAccuracy)
+	}
+	return tw.Flush()
+}
+
+// nonNil keeps empty result lists encoding as [] rather than null
+func nonNil(results []history.TestResult) []history.TestResult {
+	if results == nil {
+		return []history.TestResult{}
+	}
+	return results
+}
+
+func runExport(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("export", stderr)
+	filters := addFilterFlags(fs)
+	format := fs.String("format", "csv", "output `format` (csv, jsonl, json, monkeytype)")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	f, err := history.ParseFormat(*format)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	results, err := loadFiltered(filters, stderr)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return history.Export(stdout, results, f)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := history.Export(file, results, f); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "exported %d results to %s\n", len(results), *output)
+	return nil
+}
+
+func runImport(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("import", stderr)
+	format := fs.String("format", "auto", "input `format` (auto, monkeytype, csv)")
+	mapping := fs.String("map", "", "for csv, map fields to columns as `field=column,...`")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: taps import [flags] <file.csv>")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Imports a Monkeytype results export or any CSV with one test per row.")
+		fmt.Fprintln(stderr, "Rows whose timestamp matches an existing result are skipped.")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Flags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(stderr)
+		fmt.Fprintf(stderr, "CSV fields: %s\n", strings.Join(history.CSVFields, ", "))
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("import takes exactly one file")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *format == "auto" {
+		header, err := history.ReadCSVHeader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", fs.Arg(0), err)
+		}
+		*format = "csv"
+		if history.IsMonkeytypeCSV(header) {
+			*format = "monkeytype"
+		}
+	}
+
+	var report history.ImportReport
+	switch *format {
+	case "monkeytype":
+		report, err = history.ImportMonkeytype(bytes.NewReader(data))
+	case "csv":
+		m, perr := parseMapping(*mapping)
+		if perr != nil {
+			return perr
+		}
+		report, err = history.ImportCSV(bytes.NewReader(data), m)
+	default:
+		return usagef("unknown import format %q", *format)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", fs.Arg(0), err)
+	}
+
+	for _, w := range report.Warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+
+	store, err := openHistory(stderr)
+	if err != nil {
+		return err
+	}
+	var added int
+	if *dryRun {
+		existing, err := store.Load()
+		if err != nil {
+			return fmt.Errorf("reading history: %w", err)
+		}
+		_, added = history.Merge(existing, report.Results)
+	} else {
+		// Merge inside Update so results saved by a running taps session
+		// while importing are kept
+		err = store.Update(func(existing []history.TestResult) ([]history.TestResult, error) {
+			var merged []history.TestResult
+			merged, added = history.Merge(existing, report.Results)
+			return merged, nil
+		})
+		if err != nil {
+			return fmt.Errorf("updating history: %w", err)
+		}
+	}
+
+	verb := "imported"
+	if *dryRun {
+		verb = "would import"
+	}
+	fmt.Fprintf(stdout, "read %d rows as %s: %s %d, %d already in history, %d skipped\n",
+		report.Rows, *format, verb, added, len(report.Results)-added, report.Rows-len(report.Results))
+	return nil
+}
+
+// parseMapping parses field=column pairs separated by commas
+func parseMapping(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	if s == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, col, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, usagef("invalid --map entry %q, want field=column", pair)
+		}
+		field = strings.TrimSpace(field)
+		if !slices.Contains(history.CSVFields, field) {
+			return nil, usagef("unknown field %q in --map", field)
+		}
+		m[field] = strings.TrimSpace(col)
+	}
+	return m, nil
+}
+
+// loadConfig loads the config, reporting any problems with it on stderr
+func loadConfig(stderr io.Writer) *config.Config {
+	cfg := config.Load()
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintf(stderr, "warning: config: %s\n", w)
+	}
+	return cfg
+}
+
+func runThemes(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("themes", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg := loadConfig(stderr)
+	names := theme.ThemeNames()
+	if cfg.CustomTheme != nil {
+		names = append(names, theme.CustomName)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		marker := " "
+		if name == cfg.Theme {
+			marker = "*"
+		}
+		display := theme.GetTheme(name).Name
+		if name == theme.CustomName {
+			display = cfg.CustomTheme.Name
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", marker, name, display)
+	}
+	return tw.Flush()
+}
+
+func runLanguages(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("languages", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg := loadConfig(stderr)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, name := range typing.Languages() {
+		marker := " "
+		if name == cfg.Language {
+			marker = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%d words\n", marker, name, len(typing.GetWordList(name)))
+	}
+	return tw.Flush()
+}
+
+// describeConfig summarizes the test settings of a result on one line
+func describeConfig(r history.TestResult) string {
+	parts := []string{r.Mode}
+	switch r.Mode {
+	case "time":
+		parts = append(parts, fmt.Sprintf("%ds", r.Duration))
+	case "words":
+		parts = append(parts, fmt.Sprintf("%d words", r.WordCount))
+	case "quote":
+		if r.QuoteLength != "" {
+			parts = append(parts, r.QuoteLength)
+		}
+	}
+	parts = append(parts, r.Language)
+	if r.Punctuation {
+		parts = append(parts, "punctuation")
+	}
+	if r.Numbers {
+		parts = append(parts, "numbers")
+	}
+	if r.Difficulty != "" && r.Difficulty != "normal" {
+		parts = append(parts, r.Difficulty)
+	}
+	if r.MetricsProfile != "" && r.MetricsProfile != typing.ProfileTaps {
+		parts = append(parts, r.MetricsProfile+" metrics")
+	}
+	return strings.Join(parts, " ")
+}
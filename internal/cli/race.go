@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/meszmate/taps/internal/app"
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/race"
+	"github.com/meszmate/taps/internal/typing"
+)
+
+func runRace(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usagef("race needs a subcommand: host or join")
+	}
+	switch args[0] {
+	case "host":
+		return runRaceHost(args[1:], stderr)
+	case "join":
+		return runRaceJoin(args[1:], stderr)
+	}
+	return usagef("unknown race subcommand %q (want host or join)", args[0])
+}
+
+// addNameFlag registers the flag naming the player in a race
+func addNameFlag(fs *flag.FlagSet) *string {
+	name := os.Getenv("USER")
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	return fs.String("name", name, "`name` shown to the other racers")
+}
+
+func runRaceHost(args []string, stderr io.Writer) error {
+	fs := newFlagSet("race host", stderr)
+	port := fs.Int("port", race.DefaultPort, "TCP `port` to host the race on")
+	words := fs.Int("words", 30, "race over `count` words")
+	language := fs.String("language", "", "word list to use (see 'taps languages')")
+	punctuation := fs.Bool("punctuation", false, "add punctuation to the words")
+	numbers := fs.Bool("numbers", false, "add numbers to the words")
+	seed := fs.Int64("seed", 0, "seed the words, to race a text again (default random)")
+	name := addNameFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *words <= 0 {
+		return usagef("--words must be a positive word count")
+	}
+	if *port <= 0 || *port > 65535 {
+		return usagef("invalid port %d", *port)
+	}
+
+	cfg := loadConfig(stderr)
+	if *language == "" {
+		*language = cfg.Language
+	} else if !slices.Contains(typing.Languages(), *language) {
+		return usagef("unknown language %q (run 'taps languages' to list them)", *language)
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	if !set["seed"] {
+		*seed = time.Now().UnixNano()
+	}
+	typing.Seed(*seed)
+	r := race.Race{
+		Seed:        *seed,
+		WordCount:   *words,
+		Language:    *language,
+		Punctuation: *punctuation,
+		Numbers:     *numbers,
+		Text:        typing.GenerateWords(*words, *language, *punctuation, *numbers),
+	}
+
+	srv, err := race.Listen(":"+strconv.Itoa(*port), r)
+	if err != nil {
+		return fmt.Errorf("hosting race: %w", err)
+	}
+	defer srv.Close()
+	go srv.Serve()
+
+	// the host races through the server like everyone else
+	client, err := race.Join(net.JoinHostPort("127.0.0.1", strconv.Itoa(*port)), *name)
+	if err != nil {
+		return fmt.Errorf("joining own race: %w", err)
+	}
+	defer client.Close()
+	return runRaceApp(cfg, client, srv)
+}
+
+func runRaceJoin(args []string, stderr io.Writer) error {
+	fs := newFlagSet("race join", stderr)
+	name := addNameFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("race join needs the host's address, e.g. taps race join 192.168.1.20:%d", race.DefaultPort)
+	}
+	addr := fs.Arg(0)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(race.DefaultPort))
+	}
+
+	cfg := loadConfig(stderr)
+	client, err := race.Join(addr, *name)
+	if err != nil {
+		return fmt.Errorf("joining race: %w", err)
+	}
+	defer client.Close()
+	return runRaceApp(cfg, client, nil)
+}
+
+// runRaceApp runs the race screen until the player leaves
+func runRaceApp(cfg *config.Config, client *race.Client, srv *race.Server) error {
+	if err := history.Use(cfg.HistoryBackend); err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	p := tea.NewProgram(app.New(cfg, app.Options{RaceClient: client, RaceServer: srv}), tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
@@ -1,14 +1,18 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/adrg/xdg"
+	"github.com/meszmate/taps/internal/filelock"
+	"github.com/meszmate/taps/internal/fsutil"
 )
 
 type Config struct {
+	Version      int    `json:"version"`
 	Mode         string `json:"mode"`
 	Duration     int    `json:"duration"`
 	WordCount    int    `json:"word_count"`
@@ -27,7 +31,39 @@ type Config struct {
 	FocusMode    bool   `json:"focus_mode"`
 	SoundOnError bool   `json:"sound_on_error"`
 	QuoteLength  string `json:"quote_length"`
+	HistoryBackend string `json:"history_backend"`
+	AFKMode      string `json:"afk_mode"`
+	AFKTimeout   int    `json:"afk_timeout"` // seconds without a keystroke
+	MetricsProfile string `json:"metrics_profile"`
+	PaceCaret    string `json:"pace_caret"`
+	PaceCaretWPM int    `json:"pace_caret_wpm"` // pace of the custom pace caret
+	Goals        GoalsConfig `json:"goals"`
 	CustomTheme  *CustomThemeConfig `json:"custom_theme,omitempty"`
+
+	// session tracks fields overridden for this run only, keyed by JSON name
+	session map[string]sessionField
+	// loaded holds the fields as last read from or written to disk, used to
+	// tell this session's changes apart from other processes' when saving
+	loaded map[string]json.RawMessage
+	// warnings describes problems found while loading, for display in the UI
+	warnings []string
+}
+
+type sessionField struct {
+	saved json.RawMessage // value before the override
+	value json.RawMessage // value set by the override
+}
+
+// GoalsConfig holds the practice goals shown on the menu. A zero goal is
+// not set.
+type GoalsConfig struct {
+	MinutesPerDay int `json:"minutes_per_day"`
+	TestsPerDay   int `json:"tests_per_day"`
+	// TargetWPM is a net WPM to reach in TargetMode tests of TargetLength
+	// seconds or words
+	TargetWPM    int    `json:"target_wpm"`
+	TargetMode   string `json:"target_mode"`
+	TargetLength int    `json:"target_length"`
 }
 
 type CustomThemeConfig struct {
@@ -44,6 +80,7 @@ type CustomThemeConfig struct {
 
 func DefaultConfig() *Config {
 	return &Config{
+		Version:      SchemaVersion,
 		Mode:         DefaultMode,
 		Duration:     DefaultDuration,
 		WordCount:    DefaultWordCount,
@@ -62,6 +99,16 @@ func DefaultConfig() *Config {
 		FocusMode:    false,
 		SoundOnError: false,
 		QuoteLength:  DefaultQuoteLength,
+		HistoryBackend: DefaultHistoryBackend,
+		AFKMode:      DefaultAFKMode,
+		AFKTimeout:   DefaultAFKTimeout,
+		MetricsProfile: DefaultMetricsProfile,
+		PaceCaret:    DefaultPaceCaret,
+		PaceCaretWPM: DefaultPaceCaretWPM,
+		Goals: GoalsConfig{
+			TargetMode:   DefaultMode,
+			TargetLength: DefaultDuration,
+		},
 	}
 }
 
@@ -76,24 +123,183 @@ func Load() *Config {
 		return cfg
 	}
 	data, err := os.ReadFile(p)
-	if err != nil {
-		return cfg
+	if err == nil {
+		cfg.decode(p, data)
 	}
-	_ = json.Unmarshal(data, cfg)
+	// Record the values as read, before validation, so that Save writes
+	// back any corrected values instead of keeping the invalid ones on disk
+	cfg.loaded = cfg.fields()
+	cfg.validate()
 	return cfg
 }
 
+// Warnings returns problems found while loading the config
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// DismissWarnings clears the load warnings once they have been shown
+func (c *Config) DismissWarnings() {
+	c.warnings = nil
+}
+
+func (c *Config) warnf(format string, args ...any) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// Override applies fn to the config for this session only. Fields changed by
+// fn keep their previously saved value in Save unless they are changed again
+// afterwards, e.g. from the settings screen.
+func (c *Config) Override(fn func(c *Config)) {
+	before := c.fields()
+	fn(c)
+	after := c.fields()
+	if c.session == nil {
+		c.session = make(map[string]sessionField)
+	}
+	for k, v := range after {
+		if bytes.Equal(before[k], v) {
+			continue
+		}
+		f, ok := c.session[k]
+		if !ok {
+			f.saved = before[k]
+		}
+		f.value = v
+		c.session[k] = f
+	}
+}
+
+// fields returns the config's JSON encoding split by top-level key
+func (c *Config) fields() map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// persistedFields returns the config as it should be written to disk, with
+// session overrides that are still in effect replaced by their saved values
+func (c *Config) persistedFields() map[string]json.RawMessage {
+	fields := c.fields()
+	for k, f := range c.session {
+		if bytes.Equal(fields[k], f.value) {
+			if f.saved == nil {
+				delete(fields, k)
+			} else {
+				fields[k] = f.saved
+			}
+		}
+	}
+	return fields
+}
+
+func fromFields(fields map[string]json.RawMessage) (*Config, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	out := &Config{}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Save writes the config, merging in settings other taps processes saved
+// since this one loaded it: fields changed in this session win, every other
+// field keeps the value currently on disk.
 func (c *Config) Save() error {
 	p, err := configPath()
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+	lock, err := filelock.Acquire(p)
+	if err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(c, "", "  ")
+	defer lock.Release()
+
+	merged := c.persistedFields()
+	if c.loaded != nil {
+		if disk, err := readFields(p); err == nil {
+			for k := range disk {
+				if _, ok := merged[k]; !ok {
+					merged[k] = nil
+				}
+			}
+			for k, v := range merged {
+				if !bytes.Equal(v, c.loaded[k]) {
+					continue // changed in this session
+				}
+				if d, ok := disk[k]; ok {
+					merged[k] = d
+				} else {
+					delete(merged, k)
+				}
+			}
+		}
+	}
+
+	out, err := fromFields(merged)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(p, data, 0o644)
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(p, data, 0o644); err != nil {
+		return err
+	}
+	c.adopt(out.fields())
+	return nil
+}
+
+func readFields(p string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if err := migrate(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// adopt updates the in-memory config to the values just saved, so settings
+// merged in from other processes are not reverted by a later save. Session
+// overrides still in effect are kept.
+func (c *Config) adopt(saved map[string]json.RawMessage) {
+	current := c.fields()
+	fields := make(map[string]json.RawMessage, len(saved))
+	for k, v := range saved {
+		fields[k] = v
+	}
+	for k, f := range c.session {
+		if !bytes.Equal(current[k], f.value) {
+			// changed again since the override, so it is now a saved setting
+			delete(c.session, k)
+			continue
+		}
+		fields[k] = f.value
+		f.saved = saved[k]
+		c.session[k] = f
+	}
+
+	next, err := fromFields(fields)
+	if err != nil {
+		return
+	}
+	next.session = c.session
+	next.loaded = saved
+	next.warnings = c.warnings
+	*c = *next
 }
@@ -10,4 +10,33 @@ const (
 	DefaultCursorStyle = "line"
 	DefaultStopOnError = "off"
 	DefaultQuoteLength = "medium"
+
+	DefaultHistoryBackend = "jsonl"
+	DefaultAFKMode        = "off"
+	DefaultAFKTimeout     = 10
+	DefaultMetricsProfile = "taps"
+	DefaultPaceCaret      = "off"
+	DefaultPaceCaretWPM   = 100
+)
+
+// Allowed values for the enumerated settings
+var (
+	Modes            = []string{"time", "words", "quote", "zen"}
+	QuoteLengths     = []string{"short", "medium", "long"}
+	Difficulties     = []string{"normal", "expert", "master"}
+	StopOnErrorModes = []string{"off", "word", "letter"}
+	CursorStyles     = []string{"line", "block", "underline"}
+	HistoryBackends  = []string{"jsonl", "sqlite"}
+	// AFKModes are what happens when no key is pressed for afk_timeout
+	// seconds during a test: nothing, pause the clock or mark it invalid
+	AFKModes = []string{"off", "pause", "invalid"}
+	// MetricsProfiles are the definitions of WPM, accuracy and consistency
+	// results can be computed with
+	MetricsProfiles = []string{"taps", "monkeytype"}
+	// PaceCarets are the paces a second caret can move through the text at:
+	// none, pace_caret_wpm, the average of the last 10 tests or the personal
+	// best of the test's config, or a replay of the personal best's keystrokes
+	PaceCarets = []string{"off", "custom", "average", "pb", "replay"}
+	// GoalModes are the modes a WPM goal can be set for
+	GoalModes = []string{"time", "words"}
 )
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the config file format written by Save.
+// Files without a version field are version 0.
+const SchemaVersion = 1
+
+// migrations[i] upgrades the fields of a version i config to version i+1.
+// Append a function here whenever a field is renamed, removed or changes
+// meaning, and bump SchemaVersion.
+var migrations = []func(fields map[string]json.RawMessage) error{
+	// 0 -> 1: version field introduced, no other changes
+	func(fields map[string]json.RawMessage) error { return nil },
+}
+
+// migrate upgrades fields to SchemaVersion in place. Configs written by a
+// newer taps are left as they are; fields this version does not know are
+// ignored when decoding.
+func migrate(fields map[string]json.RawMessage) error {
+	version := 0
+	if v, ok := fields["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return fmt.Errorf("invalid version %s", v)
+		}
+	}
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](fields); err != nil {
+			return fmt.Errorf("migrating config from version %d: %w", version, err)
+		}
+	}
+	fields["version"] = json.RawMessage(fmt.Sprint(SchemaVersion))
+	return nil
+}
+
+// decode applies a saved config over the defaults in c. A file that cannot
+// be parsed is backed up and ignored; fields with values of the wrong type
+// keep their defaults.
+func (c *Config) decode(p string, data []byte) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err == nil {
+		var version int
+		_ = json.Unmarshal(fields["version"], &version)
+		if version > SchemaVersion {
+			c.warnf("config was written by a newer version of taps; unknown settings are ignored")
+		}
+		err = migrate(fields)
+	}
+	if err != nil {
+		backup := fmt.Sprintf("%s.invalid-%s", p, time.Now().Format("20060102-150405"))
+		if werr := os.WriteFile(backup, data, 0o644); werr != nil {
+			c.warnf("config could not be read (%v); using default settings", err)
+		} else {
+			c.warnf("config could not be read (%v); using default settings, the original was saved to %s", err, backup)
+		}
+		return
+	}
+
+	for k, v := range fields {
+		single, err := json.Marshal(map[string]json.RawMessage{k: v})
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(single, c); err != nil {
+			c.warnf("setting %q has an invalid value %s; using the default", k, v)
+		}
+	}
+}
+
+// validate replaces out-of-range settings with their defaults
+func (c *Config) validate() {
+	d := DefaultConfig()
+	c.checkEnum("mode", &c.Mode, Modes, d.Mode)
+	c.checkEnum("difficulty", &c.Difficulty, Difficulties, d.Difficulty)
+	c.checkEnum("stop_on_error", &c.StopOnError, StopOnErrorModes, d.StopOnError)
+	c.checkEnum("cursor_style", &c.CursorStyle, CursorStyles, d.CursorStyle)
+	c.checkEnum("quote_length", &c.QuoteLength, QuoteLengths, d.QuoteLength)
+	c.checkEnum("history_backend", &c.HistoryBackend, HistoryBackends, d.HistoryBackend)
+	c.checkEnum("afk_mode", &c.AFKMode, AFKModes, d.AFKMode)
+	c.checkEnum("metrics_profile", &c.MetricsProfile, MetricsProfiles, d.MetricsProfile)
+	c.checkEnum("pace_caret", &c.PaceCaret, PaceCarets, d.PaceCaret)
+	if c.Duration <= 0 {
+		c.warnf("invalid duration %d; using %d", c.Duration, d.Duration)
+		c.Duration = d.Duration
+	}
+	if c.WordCount <= 0 {
+		c.warnf("invalid word_count %d; using %d", c.WordCount, d.WordCount)
+		c.WordCount = d.WordCount
+	}
+	if c.AFKTimeout <= 0 {
+		c.warnf("invalid afk_timeout %d; using %d", c.AFKTimeout, d.AFKTimeout)
+		c.AFKTimeout = d.AFKTimeout
+	}
+	if c.PaceCaretWPM <= 0 {
+		c.warnf("invalid pace_caret_wpm %d; using %d", c.PaceCaretWPM, d.PaceCaretWPM)
+		c.PaceCaretWPM = d.PaceCaretWPM
+	}
+
+	g := &c.Goals
+	for _, goal := range []struct {
+		name string
+		v    *int
+	}{
+		{"goals.minutes_per_day", &g.MinutesPerDay},
+		{"goals.tests_per_day", &g.TestsPerDay},
+		{"goals.target_wpm", &g.TargetWPM},
+	} {
+		if *goal.v < 0 {
+			c.warnf("invalid %s %d; the goal is turned off", goal.name, *goal.v)
+			*goal.v = 0
+		}
+	}
+	c.checkEnum("goals.target_mode", &g.TargetMode, GoalModes, d.Goals.TargetMode)
+	if g.TargetLength <= 0 {
+		c.warnf("invalid goals.target_length %d; using %d", g.TargetLength, d.Goals.TargetLength)
+		g.TargetLength = d.Goals.TargetLength
+	}
+}
+
+func (c *Config) checkEnum(name string, v *string, allowed []string, def string) {
+	if slices.Contains(allowed, *v) {
+		return
+	}
+	c.warnf("invalid %s %q (want one of %s); using %q", name, *v, strings.Join(allowed, ", "), def)
+	*v = def
+}
@@ -0,0 +1,38 @@
+// Package filelock provides advisory locks that coordinate access to data
+// files between taps processes.
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+)
+
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes an exclusive lock for path, blocking until it is available.
+// The lock is held on a separate path+".lock" file so the data file itself
+// can be replaced by an atomic rename while locked.
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+func (l *Lock) Release() error {
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
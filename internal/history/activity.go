@@ -0,0 +1,85 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// Day is the practice done on one calendar day
+type Day struct {
+	Date     time.Time // local midnight
+	Tests    int       // completed tests
+	Practice time.Duration
+}
+
+// dayOf returns local midnight of the day containing t
+func dayOf(t time.Time) time.Time {
+	t = t.Local()
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// Activity totals the completed tests and practice time of results per
+// local day. Practice time includes tests that were not completed. Only days
+// with results are returned, oldest first.
+func Activity(results []TestResult) []Day {
+	byDay := make(map[time.Time]*Day)
+	for _, r := range results {
+		date := dayOf(r.Date)
+		d, ok := byDay[date]
+		if !ok {
+			d = &Day{Date: date}
+			byDay[date] = d
+		}
+		if r.Completed() {
+			d.Tests++
+		}
+		d.Practice += time.Duration(r.TestSeconds() * float64(time.Second))
+	}
+
+	days := make([]Day, 0, len(byDay))
+	for _, d := range byDay {
+		days = append(days, *d)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date.Before(days[j].Date)
+	})
+	return days
+}
+
+// Today returns the practice done on the day containing now
+func Today(days []Day, now time.Time) Day {
+	today := dayOf(now)
+	for i := len(days) - 1; i >= 0; i-- {
+		if days[i].Date.Equal(today) {
+			return days[i]
+		}
+	}
+	return Day{Date: today}
+}
+
+// Streak returns the number of consecutive days with completed tests ending
+// today, and the longest such run. A streak ending yesterday is still
+// current, since there is time left to practice today.
+func Streak(days []Day, now time.Time) (current, longest int) {
+	run := 0
+	var prev time.Time
+	for _, d := range days {
+		if d.Tests == 0 {
+			continue
+		}
+		if run > 0 && d.Date.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = d.Date
+		longest = max(longest, run)
+	}
+
+	today := dayOf(now)
+	if run > 0 && (prev.Equal(today) || prev.Equal(today.AddDate(0, 0, -1))) {
+		current = run
+	}
+	return current, longest
+}
@@ -0,0 +1,245 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+type Format string
+
+const (
+	FormatCSV        Format = "csv"
+	FormatJSONL      Format = "jsonl"
+	FormatJSON       Format = "json"
+	FormatMonkeytype Format = "monkeytype"
+)
+
+// Formats lists the supported export formats
+var Formats = []Format{FormatCSV, FormatJSONL, FormatJSON, FormatMonkeytype}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// Ext returns the file extension used for the format
+func (f Format) Ext() string {
+	switch f {
+	case FormatMonkeytype:
+		return "csv"
+	default:
+		return string(f)
+	}
+}
+
+// Export writes results to w in the given format, oldest first
+func Export(w io.Writer, results []TestResult, format Format) error {
+	sorted := make([]TestResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	switch format {
+	case FormatCSV:
+		return exportCSV(w, sorted)
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, r := range sorted {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sorted)
+	case FormatMonkeytype:
+		return exportMonkeytype(w, sorted)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// ExportFile writes results to a new timestamped file in the taps data
+// directory and returns its path
+func ExportFile(results []TestResult, format Format) (string, error) {
+	prefix := "history"
+	if format == FormatMonkeytype {
+		prefix = "monkeytype"
+	}
+	name := fmt.Sprintf("%s-%s.%s", prefix, time.Now().Format("20060102-150405"), format.Ext())
+	p, err := xdg.DataFile(filepath.Join("taps", "exports", name))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return "", err
+	}
+	if err := Export(f, results, format); err != nil {
+		f.Close()
+		return "", err
+	}
+	return p, f.Close()
+}
+
+var csvHeader = []string{
+	"date", "mode", "duration", "word_count", "quote_length", "language",
+	"punctuation", "numbers", "difficulty", "net_wpm", "raw_wpm", "accuracy",
+	"consistency", "correct", "incorrect", "extra", "missed", "outcome",
+}
+
+// csvDateLayout keeps the milliseconds results are told apart by, so an
+// export can be imported again without duplicating them
+const csvDateLayout = "2006-01-02T15:04:05.000Z07:00"
+
+func exportCSV(w io.Writer, results []TestResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		rec := []string{
+			r.Date.Format(csvDateLayout),
+			r.Mode,
+			strconv.Itoa(r.Duration),
+			strconv.Itoa(r.WordCount),
+			r.QuoteLength,
+			r.Language,
+			strconv.FormatBool(r.Punctuation),
+			strconv.FormatBool(r.Numbers),
+			r.Difficulty,
+			formatFloat(r.NetWPM),
+			formatFloat(r.RawWPM),
+			formatFloat(r.Accuracy),
+			formatFloat(r.Consistency),
+			strconv.Itoa(r.Correct),
+			strconv.Itoa(r.Incorrect),
+			strconv.Itoa(r.Extra),
+			strconv.Itoa(r.Missed),
+			r.outcome(),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// monkeytypeHeader matches the columns of Monkeytype's results export
+var monkeytypeHeader = []string{
+	"_id", "isPb", "wpm", "acc", "rawWpm", "consistency", "charStats", "mode",
+	"mode2", "quoteLength", "restartCount", "testDuration", "afkDuration",
+	"incompleteTestSeconds", "punctuation", "numbers", "language", "funbox",
+	"difficulty", "lazyMode", "blindMode", "bailedOut", "tags", "timestamp",
+}
+
+var monkeytypeQuoteLengths = map[string]int{"short": 0, "medium": 1, "long": 2}
+
+func exportMonkeytype(w io.Writer, results []TestResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(monkeytypeHeader); err != nil {
+		return err
+	}
+
+	// Monkeytype flags results that beat every earlier one in the same config
+	best := make(map[string]float64)
+	for _, r := range results {
+		mode2 := monkeytypeMode2(r)
+		pbKey := fmt.Sprintf("%s|%s|%s|%t|%t", r.Mode, mode2, r.Language, r.Punctuation, r.Numbers)
+		isPb := r.Completed() && r.NetWPM > best[pbKey]
+		if isPb {
+			best[pbKey] = r.NetWPM
+		}
+
+		quoteLength := -1
+		if r.Mode == "quote" {
+			if ql, ok := monkeytypeQuoteLengths[r.QuoteLength]; ok {
+				quoteLength = ql
+			}
+		}
+
+		rec := []string{
+			strconv.FormatInt(r.Date.UnixNano(), 16),
+			strconv.FormatBool(isPb),
+			formatFloat(r.NetWPM),
+			formatFloat(r.Accuracy),
+			formatFloat(r.RawWPM),
+			formatFloat(r.Consistency),
+			fmt.Sprintf("%d;%d;%d;%d", r.Correct, r.Incorrect, r.Extra, r.Missed),
+			r.Mode,
+			mode2,
+			strconv.Itoa(quoteLength),
+			"0",
+			formatFloat(r.TestSeconds()),
+			"0",
+			"0",
+			strconv.FormatBool(r.Punctuation),
+			strconv.FormatBool(r.Numbers),
+			r.Language,
+			"none",
+			r.Difficulty,
+			"false",
+			"false",
+			strconv.FormatBool(r.outcome() == OutcomeAborted),
+			"",
+			strconv.FormatInt(r.Date.UnixMilli(), 10),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func monkeytypeMode2(r TestResult) string {
+	switch r.Mode {
+	case "time":
+		return strconv.Itoa(r.Duration)
+	case "words":
+		return strconv.Itoa(r.WordCount)
+	case "zen":
+		return "zen"
+	}
+	return ""
+}
+
+// TestSeconds returns how long the test took. Results saved before the
+// elapsed time was recorded get an estimate: time tests use their configured
+// duration; other modes derive it from raw WPM and characters typed.
+func (r TestResult) TestSeconds() float64 {
+	if r.ElapsedSeconds > 0 {
+		return r.ElapsedSeconds
+	}
+	if r.Mode == "time" && r.Duration > 0 {
+		return float64(r.Duration)
+	}
+	if r.RawWPM <= 0 {
+		return 0
+	}
+	typed := float64(r.Correct + r.Incorrect + r.Extra)
+	return typed / 5 / r.RawWPM * 60
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,74 @@
+package history
+
+import "time"
+
+// Filter selects a subset of results. Zero-valued fields match everything.
+type Filter struct {
+	Mode        string
+	Length      int    // duration of time tests or word count of words tests
+	QuoteLength string // quote length of quote tests
+	Language    string
+	Punctuation *bool
+	Numbers     *bool
+	Difficulty  string
+	// MetricsProfile matches results without a profile as taps ones
+	MetricsProfile string
+	Outcome        string
+	Paused         *bool     // whether the test was paused with the pause key
+	Race           *bool     // whether the result is from a race
+	Since          time.Time // inclusive
+	Until          time.Time // exclusive
+}
+
+func (f Filter) Match(r TestResult) bool {
+	if f.Mode != "" && r.Mode != f.Mode {
+		return false
+	}
+	if f.Length != 0 && r.length() != f.Length {
+		return false
+	}
+	if f.QuoteLength != "" && r.quoteLength() != f.QuoteLength {
+		return false
+	}
+	if f.Language != "" && r.Language != f.Language {
+		return false
+	}
+	if f.Punctuation != nil && r.Punctuation != *f.Punctuation {
+		return false
+	}
+	if f.Numbers != nil && r.Numbers != *f.Numbers {
+		return false
+	}
+	if f.Difficulty != "" && r.Difficulty != f.Difficulty {
+		return false
+	}
+	if f.MetricsProfile != "" && r.metricsProfile() != f.MetricsProfile {
+		return false
+	}
+	if f.Outcome != "" && r.outcome() != f.Outcome {
+		return false
+	}
+	if f.Paused != nil && (r.PausedSeconds > 0) != *f.Paused {
+		return false
+	}
+	if f.Race != nil && (r.Source == SourceRace) != *f.Race {
+		return false
+	}
+	if !f.Since.IsZero() && r.Date.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.Date.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+func (f Filter) Apply(results []TestResult) []TestResult {
+	var out []TestResult
+	for _, r := range results {
+		if f.Match(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
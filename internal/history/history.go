@@ -1,15 +1,20 @@
 package history
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/meszmate/taps/internal/filelock"
+	"github.com/meszmate/taps/internal/fsutil"
+	"github.com/meszmate/taps/internal/typing"
 )
 
 type TestResult struct {
+	Version     int       `json:"version"`
 	Date        time.Time `json:"date"`
 	Mode        string    `json:"mode"`
 	Duration    int       `json:"duration"`
@@ -27,17 +32,123 @@ type TestResult struct {
 	Extra       int       `json:"extra"`
 	Missed      int       `json:"missed"`
 	QuoteLength string    `json:"quote_length,omitempty"`
+	Source      string    `json:"source,omitempty"` // tool an imported result came from, or SourceRace
+	// Outcome tells how the test ended, see the Outcome constants. Only
+	// completed tests count towards personal bests and averages.
+	Outcome    string `json:"outcome"`
+	FailReason string `json:"fail_reason,omitempty"` // why a failed test failed
+	// ElapsedSeconds is how long the test took, see TestSeconds
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+	// AFKSeconds is how long the test sat idle, paused or not
+	AFKSeconds float64 `json:"afk_seconds,omitempty"`
+	// MetricsProfile is how the WPM, accuracy and consistency were
+	// computed, see typing.Profiles. Empty is the taps profile.
+	MetricsProfile string `json:"metrics_profile,omitempty"`
+	// PausedSeconds is how long the test was paused with the pause key
+	PausedSeconds float64 `json:"paused_seconds,omitempty"`
+	// PerSecondWPM is the raw WPM sampled every second of the test
+	PerSecondWPM []float64 `json:"per_second_wpm,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	// Keystrokes holds the milliseconds into the test of each key and the
+	// cursor position it left, for replaying the test as a pace caret.
+	// Only the personal best of each config keeps them.
+	Keystrokes [][2]int `json:"keystrokes,omitempty"`
 }
 
+// Outcomes of a test
+const (
+	OutcomeCompleted = "completed"
+	OutcomeFailed    = "failed"  // ended by a mistake in expert or master difficulty
+	OutcomeAborted   = "aborted" // left before the end
+	OutcomeAFK       = "afk"     // invalidated for idling
+)
+
+// SourceRace marks the results of races, which never count as personal
+// bests
+const SourceRace = "race"
+
+// Outcomes lists the outcomes in display order
+var Outcomes = []string{OutcomeCompleted, OutcomeFailed, OutcomeAborted, OutcomeAFK}
+
+// Completed reports whether the test ran to its end, so that its speed
+// counts in personal bests and averages
+func (r TestResult) Completed() bool {
+	return r.outcome() == OutcomeCompleted
+}
+
+// outcome returns the outcome, treating results without one as completed
+func (r TestResult) outcome() string {
+	if r.Outcome == "" {
+		return OutcomeCompleted
+	}
+	return r.Outcome
+}
+
+// KeystrokeLog converts the keystrokes of a test to the Keystrokes format
+func KeystrokeLog(keys []typing.Keystroke) [][2]int {
+	log := make([][2]int, len(keys))
+	for i, k := range keys {
+		log[i] = [2]int{int(k.At.Milliseconds()), k.Pos}
+	}
+	return log
+}
+
+// Replay returns the keystrokes of the test, for a ghost caret
+func (r TestResult) Replay() []typing.Keystroke {
+	keys := make([]typing.Keystroke, len(r.Keystrokes))
+	for i, k := range r.Keystrokes {
+		keys[i] = typing.Keystroke{At: time.Duration(k[0]) * time.Millisecond, Pos: k[1]}
+	}
+	return keys
+}
+
+// historyPath is the JSON Lines store: one result per line, appended to as
+// tests finish so a crash can at worst damage the last line
 func historyPath() (string, error) {
+	return xdg.DataFile("taps/history.jsonl")
+}
+
+// legacyPath is the single JSON array file used by earlier versions
+func legacyPath() (string, error) {
 	return xdg.DataFile("taps/history.json")
 }
 
-func Load() ([]TestResult, error) {
+// withStore holds the history lock while fn runs, so concurrent taps
+// processes never interleave reads and writes of the store
+func withStore(fn func(p string) error) error {
 	p, err := historyPath()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	lock, err := filelock.Acquire(p)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := migrate(p); err != nil {
+		return err
 	}
+	return fn(p)
+}
+
+// jsonlStore is the default Store, a JSON Lines file that finished tests
+// are appended to. Queries read and filter the whole file.
+type jsonlStore struct{}
+
+func (jsonlStore) Load() ([]TestResult, error) {
+	var results []TestResult
+	err := withStore(func(p string) error {
+		var err error
+		results, err = read(p)
+		return err
+	})
+	return results, err
+}
+
+// read returns the results in the store, recovering from damaged lines.
+// The caller must hold the lock.
+func read(p string) ([]TestResult, error) {
 	data, err := os.ReadFile(p)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -45,33 +156,167 @@ func Load() ([]TestResult, error) {
 		}
 		return nil, err
 	}
-	var results []TestResult
-	if err := json.Unmarshal(data, &results); err != nil {
-		return nil, err
+
+	results, damaged := decodeLines(data)
+	if damaged {
+		// Keep the damaged file for inspection, then rewrite the store with
+		// every record that could be read. If the backup fails, leave the
+		// file untouched rather than risk losing anything.
+		if err := os.WriteFile(backupName(p, "corrupt"), data, 0o644); err == nil {
+			_ = writeAll(p, results)
+		}
 	}
 	return results, nil
 }
 
-func Save(results []TestResult) error {
-	p, err := historyPath()
+func (jsonlStore) Save(results []TestResult) error {
+	return withStore(func(p string) error {
+		return writeAll(p, results)
+	})
+}
+
+func (jsonlStore) Update(fn func(results []TestResult) ([]TestResult, error)) error {
+	return withStore(func(p string) error {
+		results, err := read(p)
+		if err != nil {
+			return err
+		}
+		updated, err := fn(results)
+		if err != nil {
+			return err
+		}
+		return writeAll(p, updated)
+	})
+}
+
+// Append adds a single result to the end of the file without rewriting it
+func (jsonlStore) Append(result TestResult) error {
+	result.Version = SchemaVersion
+	line, err := json.Marshal(result)
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
-		return err
+	line = append(line, '\n')
+
+	return withStore(func(p string) error {
+		f, err := os.OpenFile(p, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		// A crash mid-append can leave a partial last line; start a fresh one
+		// so this record stays readable
+		if info, err := f.Stat(); err == nil && info.Size() > 0 {
+			last := make([]byte, 1)
+			if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+				line = append([]byte{'\n'}, line...)
+			}
+		}
+		if _, err := f.Write(line); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// decodeLines parses one result per line, skipping blank lines. damaged
+// reports whether any line could not be parsed.
+func decodeLines(data []byte) (results []TestResult, damaged bool) {
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		r, err := decodeResult(line)
+		if err != nil {
+			damaged = true
+			continue
+		}
+		results = append(results, r)
+	}
+	return results, damaged
+}
+
+func encodeLines(results []TestResult) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range results {
+		r.Version = SchemaVersion
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
 	}
-	data, err := json.MarshalIndent(results, "", "  ")
+	return buf.Bytes(), nil
+}
+
+func writeAll(p string, results []TestResult) error {
+	data, err := encodeLines(results)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(p, data, 0o644)
+	return fsutil.WriteFileAtomic(p, data, 0o644)
 }
 
-func Append(result TestResult) error {
-	results, err := Load()
+// migrate converts the legacy JSON array file into the JSON Lines store the
+// first time the store is used. The legacy file is kept with a .migrated
+// suffix, or a .corrupt one if only part of it could be read.
+func migrate(p string) error {
+	legacy, err := legacyPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(legacy)
 	if err != nil {
-		results = nil
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if _, err := os.Stat(p); err == nil {
+		return nil
+	}
+
+	results, damaged := decodeLegacy(data)
+	if err := writeAll(p, results); err != nil {
+		return err
+	}
+	if damaged {
+		return os.Rename(legacy, backupName(legacy, "corrupt"))
 	}
-	results = append(results, result)
-	return Save(results)
+	return os.Rename(legacy, legacy+".migrated")
+}
+
+// decodeLegacy reads a JSON array of results, salvaging the elements before
+// the first one that fails to parse
+func decodeLegacy(data []byte) (results []TestResult, damaged bool) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, true
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return results, true
+		}
+		r, err := decodeResult(raw)
+		if err != nil {
+			return results, true
+		}
+		results = append(results, r)
+	}
+	if _, err := dec.Token(); err != nil {
+		return results, true
+	}
+	return results, false
+}
+
+func backupName(p, reason string) string {
+	return fmt.Sprintf("%s.%s-%s", p, reason, time.Now().Format("20060102-150405"))
 }
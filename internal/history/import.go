@@ -0,0 +1,385 @@
+package history
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/typing"
+)
+
+// CSVFields lists the result fields a generic CSV import can map columns to,
+// named as in the taps CSV export
+var CSVFields = csvHeader
+
+// ImportReport summarizes what an import read and anything it could not map
+type ImportReport struct {
+	Results  []TestResult
+	Rows     int
+	Warnings []string
+}
+
+// warnings collects import problems, counting repeats of the same message
+type warnings struct {
+	order  []string
+	counts map[string]int
+}
+
+func (w *warnings) add(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if w.counts == nil {
+		w.counts = make(map[string]int)
+	}
+	if w.counts[msg] == 0 {
+		w.order = append(w.order, msg)
+	}
+	w.counts[msg]++
+}
+
+func (w *warnings) list() []string {
+	out := make([]string, len(w.order))
+	for i, msg := range w.order {
+		if n := w.counts[msg]; n > 1 {
+			msg = fmt.Sprintf("%s (%d rows)", msg, n)
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// csvRows reads a CSV with a header row, returning each row keyed by column name
+func csvRows(r io.Reader) ([]string, []map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("empty CSV file")
+		}
+		return nil, nil, err
+	}
+	cleanHeader(header)
+
+	var rows []map[string]string
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(rec) {
+				row[col] = strings.TrimSpace(rec[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// IsMonkeytypeCSV reports whether a CSV header looks like a Monkeytype export
+func IsMonkeytypeCSV(header []string) bool {
+	return slices.Contains(header, "charStats") && slices.Contains(header, "mode2")
+}
+
+// ReadCSVHeader returns the header row of a CSV file
+func ReadCSVHeader(r io.Reader) ([]string, error) {
+	header, err := csv.NewReader(r).Read()
+	if err != nil {
+		return nil, err
+	}
+	cleanHeader(header)
+	return header, nil
+}
+
+// cleanHeader strips whitespace and a leading byte order mark from column names
+func cleanHeader(header []string) {
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+}
+
+var monkeytypeQuoteLengthNames = map[string]string{"0": "short", "1": "medium", "2": "long"}
+
+// ImportMonkeytype reads a Monkeytype results CSV export
+func ImportMonkeytype(r io.Reader) (ImportReport, error) {
+	_, rows, err := csvRows(r)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	var warn warnings
+	for i, row := range rows {
+		line := i + 2 // header is line 1
+		report.Rows++
+
+		ts, err := strconv.ParseInt(row["timestamp"], 10, 64)
+		if err != nil {
+			warn.add("line %d: invalid timestamp %q, skipped", line, row["timestamp"])
+			continue
+		}
+		res := TestResult{
+			Date:        time.UnixMilli(ts),
+			Mode:        row["mode"],
+			Language:    row["language"],
+			Punctuation: row["punctuation"] == "true",
+			Numbers:     row["numbers"] == "true",
+			Difficulty:  row["difficulty"],
+			Outcome:     OutcomeCompleted,
+			Source:      "monkeytype",
+			// numbers as Monkeytype computed them
+			MetricsProfile: typing.ProfileMonkeytype,
+		}
+		if row["bailedOut"] == "true" {
+			res.Outcome = OutcomeAborted
+		}
+
+		var perr error
+		parse := func(col string) float64 {
+			v, err := strconv.ParseFloat(row[col], 64)
+			if err != nil && perr == nil {
+				perr = fmt.Errorf("invalid %s %q", col, row[col])
+			}
+			return v
+		}
+		res.NetWPM = parse("wpm")
+		res.RawWPM = parse("rawWpm")
+		res.Accuracy = parse("acc")
+		res.Consistency = parse("consistency")
+		if perr != nil {
+			warn.add("line %d: %v, skipped", line, perr)
+			continue
+		}
+
+		stats := strings.Split(row["charStats"], ";")
+		if len(stats) == 4 {
+			res.Correct, _ = strconv.Atoi(stats[0])
+			res.Incorrect, _ = strconv.Atoi(stats[1])
+			res.Extra, _ = strconv.Atoi(stats[2])
+			res.Missed, _ = strconv.Atoi(stats[3])
+		} else {
+			warn.add("character counts missing")
+		}
+
+		switch res.Mode {
+		case "time":
+			res.Duration, _ = strconv.Atoi(row["mode2"])
+		case "words":
+			res.WordCount, _ = strconv.Atoi(row["mode2"])
+		case "quote":
+			ql, ok := monkeytypeQuoteLengthNames[row["quoteLength"]]
+			if !ok {
+				ql = "long"
+				warn.add("quote length %q has no taps equivalent, imported as long", row["quoteLength"])
+			}
+			res.QuoteLength = ql
+		case "zen":
+		default:
+			warn.add("mode %q has no taps equivalent, kept as is", res.Mode)
+		}
+
+		if !slices.Contains(typing.Languages(), res.Language) {
+			warn.add("language %q is not available in taps, kept as is", res.Language)
+		}
+		if res.Difficulty == "" {
+			res.Difficulty = "normal"
+		}
+		if d, err := strconv.ParseFloat(row["testDuration"], 64); err == nil && d > 0 {
+			res.ElapsedSeconds = d
+		}
+		if fb := row["funbox"]; fb != "" && fb != "none" {
+			warn.add("funbox %q is not supported, imported as a plain test", fb)
+		}
+
+		report.Results = append(report.Results, res)
+	}
+	report.Warnings = warn.list()
+	return report, nil
+}
+
+// ImportCSV reads a generic CSV file. mapping maps result fields (see
+// CSVFields) to column names; fields without an entry use the column of the
+// same name, so files written by the taps CSV export import unchanged.
+func ImportCSV(r io.Reader, mapping map[string]string) (ImportReport, error) {
+	for field := range mapping {
+		if !slices.Contains(CSVFields, field) {
+			return ImportReport{}, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	header, rows, err := csvRows(r)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	column := func(field string) string {
+		if col, ok := mapping[field]; ok {
+			return col
+		}
+		return field
+	}
+	for _, field := range []string{"date", "net_wpm"} {
+		if !slices.Contains(header, column(field)) {
+			return ImportReport{}, fmt.Errorf("no column %q for required field %s", column(field), field)
+		}
+	}
+
+	var report ImportReport
+	var warn warnings
+	var unmapped []string
+	for _, field := range CSVFields {
+		if !slices.Contains(header, column(field)) {
+			unmapped = append(unmapped, field)
+		}
+	}
+	if len(unmapped) > 0 {
+		warn.add("no columns for %s, left empty", strings.Join(unmapped, ", "))
+	}
+
+	for i, row := range rows {
+		line := i + 2
+		report.Rows++
+		get := func(field string) string { return row[column(field)] }
+
+		date, err := parseDate(get("date"))
+		if err != nil {
+			warn.add("line %d: %v, skipped", line, err)
+			continue
+		}
+		res := TestResult{
+			Date:        date,
+			Mode:        strings.ToLower(get("mode")),
+			QuoteLength: strings.ToLower(get("quote_length")),
+			Language:    get("language"),
+			Difficulty:  strings.ToLower(get("difficulty")),
+			Source:      "csv",
+		}
+
+		var perr error
+		num := func(field string) float64 {
+			s := strings.TrimSuffix(get(field), "%")
+			if s == "" {
+				return 0
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil && perr == nil {
+				perr = fmt.Errorf("invalid %s %q", field, get(field))
+			}
+			return v
+		}
+		res.Duration = int(num("duration"))
+		res.WordCount = int(num("word_count"))
+		res.NetWPM = num("net_wpm")
+		res.RawWPM = num("raw_wpm")
+		res.Accuracy = num("accuracy")
+		res.Consistency = num("consistency")
+		res.Correct = int(num("correct"))
+		res.Incorrect = int(num("incorrect"))
+		res.Extra = int(num("extra"))
+		res.Missed = int(num("missed"))
+		res.Punctuation = parseBool(get("punctuation"))
+		res.Numbers = parseBool(get("numbers"))
+		res.Outcome = strings.ToLower(get("outcome"))
+		if res.Outcome == "" {
+			res.Outcome = OutcomeCompleted
+		} else if !slices.Contains(Outcomes, res.Outcome) {
+			warn.add("line %d: unknown outcome %q, imported as completed", line, res.Outcome)
+			res.Outcome = OutcomeCompleted
+		}
+		if perr != nil {
+			warn.add("line %d: %v, skipped", line, perr)
+			continue
+		}
+
+		if res.Mode != "" && !slices.Contains(config.Modes, res.Mode) {
+			warn.add("mode %q has no taps equivalent, kept as is", res.Mode)
+		}
+		if res.Language != "" && !slices.Contains(typing.Languages(), res.Language) {
+			warn.add("language %q is not available in taps, kept as is", res.Language)
+		}
+		if res.Difficulty == "" {
+			res.Difficulty = "normal"
+		}
+
+		report.Results = append(report.Results, res)
+	}
+	report.Warnings = warn.list()
+	return report, nil
+}
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseDate accepts common date layouts and Unix timestamps in seconds or
+// milliseconds
+func parseDate(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+func parseBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "yes", "1", "on":
+		return true
+	}
+	return false
+}
+
+// Merge adds imported results to existing ones, skipping any whose timestamp
+// matches an existing result at the precision the import provides: to the
+// millisecond, or to the second for sources without fractional seconds. It
+// returns the merged results sorted by date and how many were added.
+func Merge(existing, imported []TestResult) ([]TestResult, int) {
+	seen := make(map[int64]bool, len(existing))
+	seenSecond := make(map[int64]bool, len(existing))
+	for _, r := range existing {
+		seen[r.Date.UnixMilli()] = true
+		seenSecond[r.Date.Unix()] = true
+	}
+
+	merged := make([]TestResult, len(existing), len(existing)+len(imported))
+	copy(merged, existing)
+	added := 0
+	for _, r := range imported {
+		k := r.Date.UnixMilli()
+		if seen[k] || r.Date.Nanosecond() == 0 && seenSecond[r.Date.Unix()] {
+			continue
+		}
+		seen[k] = true
+		seenSecond[r.Date.Unix()] = true
+		merged = append(merged, r)
+		added++
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Date.Before(merged[j].Date)
+	})
+	return merged, added
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the result records written to the store.
+// Records without a version field are version 0.
+const SchemaVersion = 2
+
+// migrations[i] upgrades the fields of a version i record to version i+1.
+// Append a function here whenever a field is renamed, removed or changes
+// meaning, and bump SchemaVersion.
+var migrations = []func(fields map[string]json.RawMessage) error{
+	// 0 -> 1: version field introduced, no other changes
+	func(fields map[string]json.RawMessage) error { return nil },
+
+	// 1 -> 2: the failed flag became the outcome
+	func(fields map[string]json.RawMessage) error {
+		outcome := OutcomeCompleted
+		if string(fields["failed"]) == "true" {
+			outcome = OutcomeFailed
+		}
+		delete(fields, "failed")
+		data, err := json.Marshal(outcome)
+		if err != nil {
+			return err
+		}
+		fields["outcome"] = data
+		return nil
+	},
+}
+
+// decodeResult parses a stored record, upgrading it to SchemaVersion
+func decodeResult(raw []byte) (TestResult, error) {
+	var r TestResult
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return r, err
+	}
+	if r.Version >= SchemaVersion {
+		return r, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return r, err
+	}
+	for v := r.Version; v < SchemaVersion; v++ {
+		if err := migrations[v](fields); err != nil {
+			return r, fmt.Errorf("migrating result from version %d: %w", v, err)
+		}
+	}
+	fields["version"] = json.RawMessage(fmt.Sprint(SchemaVersion))
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return r, err
+	}
+	r = TestResult{}
+	err = json.Unmarshal(data, &r)
+	return r, err
+}
@@ -0,0 +1,490 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema keeps the fields results are filtered and ranked by in
+// indexed columns, and the fields stats are computed from in plain columns.
+// The full result is stored as JSON in data, so fields added to TestResult
+// later need no schema change unless stats use them. Columns added since
+// are created by sqliteMigrations.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	id           INTEGER PRIMARY KEY,
+	date         INTEGER NOT NULL, -- Unix milliseconds
+	mode         TEXT NOT NULL,
+	duration     INTEGER NOT NULL,
+	word_count   INTEGER NOT NULL,
+	length       INTEGER NOT NULL, -- duration or word count, by mode
+	quote_length TEXT NOT NULL,    -- quote tests only
+	language     TEXT NOT NULL,
+	punctuation  INTEGER NOT NULL,
+	numbers      INTEGER NOT NULL,
+	difficulty   TEXT NOT NULL,
+	net_wpm      REAL NOT NULL,
+	correct      INTEGER NOT NULL,
+	data         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_date ON results (date);
+CREATE INDEX IF NOT EXISTS results_mode ON results (mode, date);
+`
+
+// sqliteMigrations[i] upgrades a database from user_version i to i+1
+var sqliteMigrations = []string{
+	`CREATE INDEX IF NOT EXISTS results_config ON results
+		(mode, length, language, punctuation, numbers, difficulty, net_wpm);`,
+
+	// personal bests are kept per full config, including the quote length
+	`UPDATE results SET quote_length = '' WHERE mode != 'quote';
+	DROP INDEX IF EXISTS results_config;
+	CREATE INDEX results_config ON results
+		(mode, length, quote_length, language, punctuation, numbers, difficulty, net_wpm);`,
+
+	// stats are computed from columns instead of decoding data
+	`ALTER TABLE results ADD COLUMN raw_wpm REAL NOT NULL DEFAULT 0;
+	ALTER TABLE results ADD COLUMN accuracy REAL NOT NULL DEFAULT 0;
+	ALTER TABLE results ADD COLUMN consistency REAL NOT NULL DEFAULT 0;
+	ALTER TABLE results ADD COLUMN incorrect INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE results ADD COLUMN extra INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE results ADD COLUMN elapsed_seconds REAL NOT NULL DEFAULT 0;
+	ALTER TABLE results ADD COLUMN failed INTEGER NOT NULL DEFAULT 0;
+	UPDATE results SET
+		raw_wpm = COALESCE(json_extract(data, '$.raw_wpm'), 0),
+		accuracy = COALESCE(json_extract(data, '$.accuracy'), 0),
+		consistency = COALESCE(json_extract(data, '$.consistency'), 0),
+		incorrect = COALESCE(json_extract(data, '$.incorrect'), 0),
+		extra = COALESCE(json_extract(data, '$.extra'), 0),
+		elapsed_seconds = COALESCE(json_extract(data, '$.elapsed_seconds'), 0),
+		failed = COALESCE(json_extract(data, '$.failed'), 0);`,
+
+	// the failed flag became the outcome
+	`ALTER TABLE results ADD COLUMN outcome TEXT NOT NULL DEFAULT 'completed';
+	UPDATE results SET outcome = 'failed' WHERE failed;
+	ALTER TABLE results DROP COLUMN failed;`,
+
+	// tests paused with the pause key can be filtered out
+	`ALTER TABLE results ADD COLUMN paused_seconds REAL NOT NULL DEFAULT 0;
+	UPDATE results SET paused_seconds = COALESCE(json_extract(data, '$.paused_seconds'), 0);`,
+
+	// personal bests are kept per metrics profile
+	`ALTER TABLE results ADD COLUMN metrics_profile TEXT NOT NULL DEFAULT 'taps';
+	UPDATE results SET metrics_profile = COALESCE(NULLIF(json_extract(data, '$.metrics_profile'), ''), 'taps');
+	DROP INDEX IF EXISTS results_config;
+	CREATE INDEX results_config ON results
+		(mode, length, quote_length, language, punctuation, numbers, difficulty, metrics_profile, net_wpm);`,
+
+	// race results stay out of personal bests
+	`ALTER TABLE results ADD COLUMN source TEXT NOT NULL DEFAULT '';
+	UPDATE results SET source = COALESCE(json_extract(data, '$.source'), '');`,
+}
+
+// sqliteStore keeps results in an embedded SQLite database, so filters,
+// aggregates and personal bests are answered from indexes instead of
+// reading the whole history
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func sqlitePath() (string, error) {
+	return xdg.DataFile("taps/history.db")
+}
+
+// openSQLite opens the database, creating it from the JSON Lines history
+// the first time
+func openSQLite() (*sqliteStore, error) {
+	p, err := sqlitePath()
+	if err != nil {
+		return nil, err
+	}
+	_, statErr := os.Stat(p)
+	created := errors.Is(statErr, os.ErrNotExist)
+
+	// Wait for other taps processes instead of failing while they write,
+	// and take the write lock when a transaction starts so Update cannot
+	// interleave with another writer
+	db, err := sql.Open("sqlite", p+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	s := &sqliteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", p, err)
+	}
+
+	if created {
+		results, err := jsonlStore{}.Load()
+		if err == nil && len(results) > 0 {
+			err = s.Save(results)
+		}
+		if err != nil {
+			db.Close()
+			os.Remove(p)
+			return nil, fmt.Errorf("importing history into %s: %w", p, err)
+		}
+	}
+	return s, nil
+}
+
+// migrate creates the tables and applies the migrations the database has
+// not had yet
+func (s *sqliteStore) migrate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(sqliteMigrations) {
+		return nil
+	}
+	for _, m := range sqliteMigrations[version:] {
+		if _, err := tx.Exec(m); err != nil {
+			return fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+		version++
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// where returns the SQL condition for f and its arguments, joined with any
+// extra conditions
+func (f Filter) where(extra ...string) (string, []any) {
+	conds := extra
+	var args []any
+	add := func(cond string, arg any) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if f.Mode != "" {
+		add("mode = ?", f.Mode)
+	}
+	if f.Length != 0 {
+		add("length = ?", f.Length)
+	}
+	if f.QuoteLength != "" {
+		add("quote_length = ?", f.QuoteLength)
+	}
+	if f.Language != "" {
+		add("language = ?", f.Language)
+	}
+	if f.Punctuation != nil {
+		add("punctuation = ?", *f.Punctuation)
+	}
+	if f.Numbers != nil {
+		add("numbers = ?", *f.Numbers)
+	}
+	if f.Difficulty != "" {
+		add("difficulty = ?", f.Difficulty)
+	}
+	if f.MetricsProfile != "" {
+		add("metrics_profile = ?", f.MetricsProfile)
+	}
+	if f.Outcome != "" {
+		add("outcome = ?", f.Outcome)
+	}
+	if f.Paused != nil {
+		add("(paused_seconds > 0) = ?", *f.Paused)
+	}
+	if f.Race != nil {
+		add("(source = 'race') = ?", *f.Race)
+	}
+	if !f.Since.IsZero() {
+		add("date >= ?", f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		add("date < ?", f.Until.UnixMilli())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *sqliteStore) Load() ([]TestResult, error) {
+	return s.Query(Filter{})
+}
+
+func (s *sqliteStore) Query(f Filter) ([]TestResult, error) {
+	where, args := f.where()
+	return s.query("SELECT data FROM results"+where+" ORDER BY date, id", args...)
+}
+
+// query runs a query selecting the data column and decodes each row
+func (s *sqliteStore) query(q string, args ...any) ([]TestResult, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanResults(rows)
+}
+
+func scanResults(rows *sql.Rows) ([]TestResult, error) {
+	defer rows.Close()
+	var results []TestResult
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		r, err := decodeResult([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// Stats reads only the columns the stats are computed from, which is much
+// cheaper than decoding every stored result, and fetches the full personal
+// best afterwards
+func (s *sqliteStore) Stats(f Filter) (Stats, error) {
+	where, args := f.where()
+	rows, err := s.db.Query(`
+		SELECT id, date, mode, duration, language, net_wpm, correct, raw_wpm,
+			accuracy, consistency, incorrect, extra, elapsed_seconds, outcome, source
+		FROM results`+where+" ORDER BY date, id", args...)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var results []TestResult
+	for rows.Next() {
+		var id, date int64
+		var r TestResult
+		err := rows.Scan(&id, &date, &r.Mode, &r.Duration, &r.Language, &r.NetWPM, &r.Correct,
+			&r.RawWPM, &r.Accuracy, &r.Consistency, &r.Incorrect, &r.Extra, &r.ElapsedSeconds, &r.Outcome,
+			&r.Source)
+		if err != nil {
+			return Stats{}, err
+		}
+		r.Date = time.UnixMilli(date)
+		ids = append(ids, id)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return Stats{}, err
+	}
+
+	st := CalculateStats(results)
+	for i := range results {
+		if st.PersonalBest != &results[i] {
+			continue
+		}
+		best, err := s.query("SELECT data FROM results WHERE id = ?", ids[i])
+		if err != nil {
+			return Stats{}, err
+		}
+		if len(best) > 0 {
+			st.PersonalBest = &best[0]
+		}
+		break
+	}
+	return st, nil
+}
+
+func (s *sqliteStore) PersonalBests(f Filter) ([]TestResult, error) {
+	// SQLite takes the other selected columns from the row holding the MAX
+	where, args := f.where("outcome = 'completed'", "source != 'race'")
+	return s.query(`
+		SELECT r.data FROM results r JOIN (
+			SELECT id, MAX(net_wpm) FROM results`+where+`
+			GROUP BY mode, length, quote_length, language, punctuation, numbers, difficulty, metrics_profile
+		) best ON r.id = best.id
+		ORDER BY r.mode, r.length, r.quote_length, r.language, r.punctuation, r.numbers, r.difficulty,
+			r.metrics_profile`, args...)
+}
+
+// Activity reads only the columns practice time is computed from
+func (s *sqliteStore) Activity(f Filter) ([]Day, error) {
+	where, args := f.where()
+	rows, err := s.db.Query(`
+		SELECT date, mode, duration, correct, incorrect, extra, raw_wpm,
+			elapsed_seconds, outcome
+		FROM results`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []TestResult
+	for rows.Next() {
+		var date int64
+		var r TestResult
+		err := rows.Scan(&date, &r.Mode, &r.Duration, &r.Correct, &r.Incorrect, &r.Extra,
+			&r.RawWPM, &r.ElapsedSeconds, &r.Outcome)
+		if err != nil {
+			return nil, err
+		}
+		r.Date = time.UnixMilli(date)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return Activity(results), nil
+}
+
+func (s *sqliteStore) Append(result TestResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertResults(tx, []TestResult{result}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Save(results []TestResult) error {
+	return s.Update(func([]TestResult) ([]TestResult, error) {
+		return results, nil
+	})
+}
+
+func (s *sqliteStore) Update(fn func(results []TestResult) ([]TestResult, error)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT data FROM results ORDER BY date, id")
+	if err != nil {
+		return err
+	}
+	results, err := scanResults(rows)
+	if err != nil {
+		return err
+	}
+
+	updated, err := fn(results)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM results"); err != nil {
+		return err
+	}
+	if err := insertResults(tx, updated); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Remove(date time.Time) error {
+	return s.editAt(date, "DELETE FROM results WHERE id = ?")
+}
+
+// SetTags edits the tags in place in each matching row's data
+func (s *sqliteStore) SetTags(date time.Time, tags []string) error {
+	if len(tags) == 0 {
+		return s.editAt(date, "UPDATE results SET data = json_remove(data, '$.tags') WHERE id = ?")
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	return s.editAt(date, "UPDATE results SET data = json_set(data, '$.tags', json(?)) WHERE id = ?", string(encoded))
+}
+
+func (s *sqliteStore) ClearKeystrokes(date time.Time) error {
+	return s.editAt(date, "UPDATE results SET data = json_remove(data, '$.keystrokes') WHERE id = ?")
+}
+
+// editAt runs stmt, with args followed by the row id, on each result
+// recorded at exactly date. The date column only keeps milliseconds, so the
+// rows it matches are narrowed down by the full date in their data, as the
+// JSON Lines store compares them.
+func (s *sqliteStore) editAt(date time.Time, stmt string, args ...any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, json_extract(data, '$.date') FROM results WHERE date = ?", date.UnixMilli())
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var stored string
+		if err := rows.Scan(&id, &stored); err != nil {
+			rows.Close()
+			return err
+		}
+		if t, err := time.Parse(time.RFC3339Nano, stored); err == nil && t.Equal(date) {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec(stmt, append(args, id)...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func insertResults(tx *sql.Tx, results []TestResult) error {
+	stmt, err := tx.Prepare(`INSERT INTO results
+		(date, mode, duration, word_count, length, quote_length, language,
+		 punctuation, numbers, difficulty, net_wpm, correct, raw_wpm, accuracy,
+		 consistency, incorrect, extra, elapsed_seconds, outcome, paused_seconds,
+		 metrics_profile, source, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range results {
+		r.Version = SchemaVersion
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.Exec(r.Date.UnixMilli(), r.Mode, r.Duration, r.WordCount, r.length(),
+			r.quoteLength(), r.Language, r.Punctuation, r.Numbers, r.Difficulty, r.NetWPM, r.Correct,
+			r.RawWPM, r.Accuracy, r.Consistency, r.Incorrect, r.Extra, r.ElapsedSeconds, r.outcome(),
+			r.PausedSeconds, r.metricsProfile(), r.Source, string(data))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -1,14 +1,67 @@
 package history
 
-import "sort"
+import (
+	"cmp"
+	"math"
+	"sort"
+	"time"
 
+	"github.com/meszmate/taps/internal/typing"
+)
+
+// Stats summarizes results. Speed, accuracy and consistency figures only
+// count completed tests; the outcome counts, words and time typed cover
+// every test.
 type Stats struct {
-	TotalTests   int
-	AverageWPM   float64
-	BestWPM      float64
-	TotalWords   int
-	Last10Avg    float64
-	PersonalBest *TestResult
+	TotalTests   int         `json:"total_tests"`
+	Completed    int         `json:"completed"`
+	Failed       int         `json:"failed"`
+	Aborted      int         `json:"aborted"`
+	AFK          int         `json:"afk"`
+	AverageWPM   float64     `json:"average_wpm"`
+	BestWPM      float64     `json:"best_wpm"` // of PersonalBest, so races are left out
+	TotalWords   int         `json:"total_words"`
+	Last10Avg    float64     `json:"last_10_avg"`
+	PersonalBest *TestResult `json:"personal_best,omitempty"`
+
+	MedianWPM          float64     `json:"median_wpm"`
+	Percentiles        Percentiles `json:"wpm_percentiles"`
+	StdDevWPM          float64     `json:"stddev_wpm"`
+	AverageAccuracy    float64     `json:"average_accuracy"`
+	AverageConsistency float64     `json:"average_consistency"`
+	// TotalSeconds is the time spent typing, see TestResult.TestSeconds
+	TotalSeconds float64 `json:"total_seconds"`
+	// ImprovementPerWeek is the slope of net WPM over time, fitted by least
+	// squares. It is zero until the results span at least a week.
+	ImprovementPerWeek float64 `json:"improvement_per_week"`
+
+	ByMode     []GroupStats `json:"by_mode"`
+	ByLanguage []GroupStats `json:"by_language"`
+}
+
+// Rate returns the share of all tests, in percent, that n tests make up
+func (s Stats) Rate(n int) float64 {
+	if s.TotalTests == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(s.TotalTests)
+}
+
+// Percentiles of net WPM: P90 is the speed 90% of tests were slower than
+type Percentiles struct {
+	P10 float64 `json:"p10"`
+	P25 float64 `json:"p25"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+}
+
+// GroupStats summarizes the results sharing a mode or language
+type GroupStats struct {
+	Name            string  `json:"name"`
+	Tests           int     `json:"tests"`
+	AverageWPM      float64 `json:"average_wpm"`
+	BestWPM         float64 `json:"best_wpm"`
+	AverageAccuracy float64 `json:"average_accuracy"`
 }
 
 func CalculateStats(results []TestResult) Stats {
@@ -21,20 +74,60 @@ func CalculateStats(results []TestResult) Stats {
 	}
 
 	totalWPM := 0.0
+	totalAcc, totalCons := 0.0, 0.0
+	var wpms []float64
+	var completed []TestResult
 	for i := range results {
 		r := &results[i]
+		s.TotalSeconds += r.TestSeconds()
+		s.TotalWords += r.Correct / 5 // approximate words
+		switch r.outcome() {
+		case OutcomeFailed:
+			s.Failed++
+		case OutcomeAborted:
+			s.Aborted++
+		case OutcomeAFK:
+			s.AFK++
+		}
+		if !r.Completed() {
+			continue
+		}
+		s.Completed++
+		completed = append(completed, *r)
 		totalWPM += r.NetWPM
-		if r.NetWPM > s.BestWPM {
+		totalAcc += r.Accuracy
+		totalCons += r.Consistency
+		wpms = append(wpms, r.NetWPM)
+		if r.ranked() && r.NetWPM > s.BestWPM {
 			s.BestWPM = r.NetWPM
 			s.PersonalBest = r
 		}
-		s.TotalWords += r.Correct / 5 // approximate words
 	}
-	s.AverageWPM = totalWPM / float64(len(results))
+	if s.Completed == 0 {
+		return s
+	}
+	n := float64(s.Completed)
+	s.AverageWPM = totalWPM / n
+	s.AverageAccuracy = totalAcc / n
+	s.AverageConsistency = totalCons / n
+
+	// Spread
+	sort.Float64s(wpms)
+	s.MedianWPM = percentile(wpms, 50)
+	s.Percentiles = Percentiles{
+		P10: percentile(wpms, 10),
+		P25: percentile(wpms, 25),
+		P75: percentile(wpms, 75),
+		P90: percentile(wpms, 90),
+	}
+	variance := 0.0
+	for _, w := range wpms {
+		variance += (w - s.AverageWPM) * (w - s.AverageWPM)
+	}
+	s.StdDevWPM = math.Sqrt(variance / n)
 
 	// Last 10 average
-	sorted := make([]TestResult, len(results))
-	copy(sorted, results)
+	sorted := completed
 	sort.Slice(sorted, func(i, j int) bool {
 		return sorted[i].Date.After(sorted[j].Date)
 	})
@@ -49,20 +142,172 @@ func CalculateStats(results []TestResult) Stats {
 	}
 	s.Last10Avg = last10Total / float64(count)
 
+	s.ImprovementPerWeek = improvementPerWeek(sorted)
+	s.ByMode = groupStats(completed, func(r TestResult) string { return r.Mode })
+	s.ByLanguage = groupStats(completed, func(r TestResult) string { return r.Language })
+
 	return s
 }
 
-func PersonalBestForConfig(results []TestResult, mode, language string, duration, wordCount int) *TestResult {
+// percentile returns the p-th percentile of sorted values, interpolating
+// between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(rank)
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// improvementPerWeek fits a line to net WPM over time and returns its slope
+// in WPM per week
+func improvementPerWeek(results []TestResult) float64 {
+	if len(results) < 2 {
+		return 0
+	}
+	first, last := results[0].Date, results[0].Date
+	for _, r := range results {
+		if r.Date.Before(first) {
+			first = r.Date
+		}
+		if r.Date.After(last) {
+			last = r.Date
+		}
+	}
+	if last.Sub(first) < 7*24*time.Hour {
+		return 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, r := range results {
+		x := r.Date.Sub(first).Hours() / (7 * 24)
+		sumX += x
+		sumY += r.NetWPM
+		sumXY += x * r.NetWPM
+		sumXX += x * x
+	}
+	n := float64(len(results))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// groupStats summarizes results per key, busiest group first
+func groupStats(results []TestResult, key func(TestResult) string) []GroupStats {
+	index := make(map[string]int)
+	var groups []GroupStats
+	accuracy := make(map[string]float64)
+	for _, r := range results {
+		k := key(r)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, GroupStats{Name: k})
+		}
+		g := &groups[i]
+		g.Tests++
+		g.AverageWPM += r.NetWPM
+		if r.ranked() {
+			g.BestWPM = max(g.BestWPM, r.NetWPM)
+		}
+		accuracy[k] += r.Accuracy
+	}
+	for i := range groups {
+		g := &groups[i]
+		g.AverageAccuracy = accuracy[g.Name] / float64(g.Tests)
+		g.AverageWPM /= float64(g.Tests)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Tests != groups[j].Tests {
+			return groups[i].Tests > groups[j].Tests
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// ConfigKey identifies the test settings under which results are compared
+// for personal bests
+type ConfigKey struct {
+	Mode        string
+	Length      int    // duration of time tests or word count of words tests
+	QuoteLength string // quote tests only
+	Language    string
+	Punctuation bool
+	Numbers     bool
+	Difficulty  string
+	// MetricsProfile keeps results scored differently from competing
+	MetricsProfile string
+}
+
+// ConfigKey returns the settings r was taken with
+func (r TestResult) ConfigKey() ConfigKey {
+	return ConfigKey{
+		Mode:           r.Mode,
+		Length:         r.length(),
+		QuoteLength:    r.quoteLength(),
+		Language:       r.Language,
+		Punctuation:    r.Punctuation,
+		Numbers:        r.Numbers,
+		Difficulty:     r.Difficulty,
+		MetricsProfile: r.metricsProfile(),
+	}
+}
+
+// Filter returns a filter matching the results taken with these settings,
+// leaving out races
+func (k ConfigKey) Filter() Filter {
+	race := false
+	return Filter{
+		Mode:           k.Mode,
+		Length:         k.Length,
+		QuoteLength:    k.QuoteLength,
+		Language:       k.Language,
+		Punctuation:    &k.Punctuation,
+		Numbers:        &k.Numbers,
+		Difficulty:     k.Difficulty,
+		MetricsProfile: k.MetricsProfile,
+		Race:           &race,
+	}
+}
+
+func (k ConfigKey) less(o ConfigKey) bool {
+	return cmp.Or(
+		cmp.Compare(k.Mode, o.Mode),
+		cmp.Compare(k.Length, o.Length),
+		cmp.Compare(k.QuoteLength, o.QuoteLength),
+		cmp.Compare(k.Language, o.Language),
+		compareBool(k.Punctuation, o.Punctuation),
+		compareBool(k.Numbers, o.Numbers),
+		cmp.Compare(k.Difficulty, o.Difficulty),
+		cmp.Compare(k.MetricsProfile, o.MetricsProfile),
+	) < 0
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// PersonalBestForConfig returns the fastest completed result taken with the
+// settings in key, or nil if there is none
+func PersonalBestForConfig(results []TestResult, key ConfigKey) *TestResult {
 	var best *TestResult
 	for i := range results {
 		r := &results[i]
-		if r.Mode != mode || r.Language != language {
-			continue
-		}
-		if mode == "time" && r.Duration != duration {
-			continue
-		}
-		if mode == "words" && r.WordCount != wordCount {
+		if !r.ranked() || r.ConfigKey() != key {
 			continue
 		}
 		if best == nil || r.NetWPM > best.NetWPM {
@@ -71,3 +316,69 @@ func PersonalBestForConfig(results []TestResult, mode, language string, duration
 	}
 	return best
 }
+
+// PersonalBests returns the best completed result for each distinct config,
+// leaving out races, ordered by mode, then duration or word count, then the
+// remaining settings
+func PersonalBests(results []TestResult) []TestResult {
+	best := make(map[ConfigKey]TestResult)
+	for _, r := range results {
+		if !r.ranked() {
+			continue
+		}
+		k := r.ConfigKey()
+		if b, ok := best[k]; !ok || r.NetWPM > b.NetWPM {
+			best[k] = r
+		}
+	}
+
+	keys := make([]ConfigKey, 0, len(best))
+	for k := range best {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+
+	out := make([]TestResult, len(keys))
+	for i, k := range keys {
+		out[i] = best[k]
+	}
+	return out
+}
+
+// length returns the duration of time tests and the word count of words
+// tests, the setting results of the same mode are compared by
+func (r TestResult) length() int {
+	switch r.Mode {
+	case "time":
+		return r.Duration
+	case "words":
+		return r.WordCount
+	}
+	return 0
+}
+
+// quoteLength returns the quote length of quote tests. Other tests record
+// the quote length setting too, but it does not affect them.
+func (r TestResult) quoteLength() string {
+	if r.Mode == "quote" {
+		return r.QuoteLength
+	}
+	return ""
+}
+
+// ranked reports whether r can be a personal best: a completed test that
+// was not raced
+func (r TestResult) ranked() bool {
+	return r.Completed() && r.Source != SourceRace
+}
+
+// metricsProfile returns the metrics profile r was scored with, treating
+// results without one as scored by the taps profile
+func (r TestResult) metricsProfile() string {
+	if r.MetricsProfile == "" {
+		return typing.ProfileTaps
+	}
+	return r.MetricsProfile
+}
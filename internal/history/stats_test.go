@@ -0,0 +1,65 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// openStores opens each backend on an empty history
+func openStores(t *testing.T) map[string]Store {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	stores := make(map[string]Store)
+	for _, backend := range []string{BackendJSONL, BackendSQLite} {
+		s, err := Open(backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		stores[backend] = s
+	}
+	return stores
+}
+
+func TestStatsLeaveOutRaces(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	test := TestResult{Date: date, Mode: "words", WordCount: 30, Language: "english", NetWPM: 60, Outcome: OutcomeCompleted}
+	raced := test
+	raced.Date = date.Add(time.Minute)
+	raced.NetWPM = 90
+	raced.Source = SourceRace
+
+	for backend, s := range openStores(t) {
+		for _, r := range []TestResult{test, raced} {
+			if err := s.Append(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		st, err := s.Stats(Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.BestWPM != 60 || st.PersonalBest == nil || st.PersonalBest.Source == SourceRace {
+			t.Errorf("%s: best wpm %v, personal best %+v, want the 60 wpm test", backend, st.BestWPM, st.PersonalBest)
+		}
+		if st.Completed != 2 || st.AverageWPM != 75 {
+			t.Errorf("%s: %d completed averaging %v, want races counted in the average", backend, st.Completed, st.AverageWPM)
+		}
+		if len(st.ByMode) != 1 || st.ByMode[0].BestWPM != 60 {
+			t.Errorf("%s: by mode %+v, want a best of 60", backend, st.ByMode)
+		}
+
+		pbs, err := s.PersonalBests(Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pbs) != 1 || pbs[0].NetWPM != 60 {
+			t.Errorf("%s: personal bests %+v, want the 60 wpm test", backend, pbs)
+		}
+	}
+}
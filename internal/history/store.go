@@ -0,0 +1,214 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store persists test results. Results are returned oldest first.
+type Store interface {
+	Load() ([]TestResult, error)
+	// Query returns the results matching f
+	Query(f Filter) ([]TestResult, error)
+	// Stats aggregates the results matching f
+	Stats(f Filter) (Stats, error)
+	// PersonalBests returns the best result per config among those matching
+	// f, ordered as by the PersonalBests function
+	PersonalBests(f Filter) ([]TestResult, error)
+	// Activity totals the results matching f per day, as by the Activity
+	// function
+	Activity(f Filter) ([]Day, error)
+	// Append adds a single result
+	Append(result TestResult) error
+	// Remove deletes the result recorded at date
+	Remove(date time.Time) error
+	// SetTags replaces the tags of the result recorded at date
+	SetTags(date time.Time, tags []string) error
+	// ClearKeystrokes drops the keystroke log of the result recorded at date
+	ClearKeystrokes(date time.Time) error
+	// Save replaces every stored result with results
+	Save(results []TestResult) error
+	// Update replaces the stored results with the result of fn, which
+	// receives the current results while other writers are locked out
+	Update(fn func(results []TestResult) ([]TestResult, error)) error
+	Close() error
+}
+
+// Backend names, as used by the history_backend setting
+const (
+	BackendJSONL  = "jsonl"
+	BackendSQLite = "sqlite"
+)
+
+// Open opens the store for the named backend
+func Open(backend string) (Store, error) {
+	switch backend {
+	case BackendJSONL, "":
+		return jsonlStore{}, nil
+	case BackendSQLite:
+		return openSQLite()
+	}
+	return nil, fmt.Errorf("unknown history backend %q", backend)
+}
+
+var (
+	mu            sync.Mutex
+	active        Store
+	activeBackend string
+)
+
+// Use makes backend the store used by the package-level functions. When
+// another backend was in use, its results are copied over first so that
+// switching backends keeps the history.
+func Use(backend string) error {
+	if backend == "" {
+		backend = BackendJSONL
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if active != nil && activeBackend == backend {
+		return nil
+	}
+
+	s, err := Open(backend)
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		results, err := active.Load()
+		if err == nil {
+			err = s.Save(results)
+		}
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("moving history to %s: %w", backend, err)
+		}
+		active.Close()
+	}
+	active, activeBackend = s, backend
+	return nil
+}
+
+// Default returns the store selected with Use, or the JSON Lines store if
+// none was selected
+func Default() Store {
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		active, activeBackend = jsonlStore{}, BackendJSONL
+	}
+	return active
+}
+
+// Backend returns the name of the backend in use
+func Backend() string {
+	Default()
+	mu.Lock()
+	defer mu.Unlock()
+	return activeBackend
+}
+
+// Close closes the store in use
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		return nil
+	}
+	err := active.Close()
+	active, activeBackend = nil, ""
+	return err
+}
+
+func Load() ([]TestResult, error) { return Default().Load() }
+
+// Save replaces the whole history with results. Prefer Update for changes
+// based on previously loaded results, so results appended by other taps
+// processes in the meantime are kept.
+func Save(results []TestResult) error { return Default().Save(results) }
+
+// Update rewrites the history with the result of fn, which receives the
+// current contents of the store while the lock is held
+func Update(fn func(results []TestResult) ([]TestResult, error)) error {
+	return Default().Update(fn)
+}
+
+// Append adds a single result to the store without rewriting it
+func Append(result TestResult) error { return Default().Append(result) }
+
+func (s jsonlStore) Query(f Filter) ([]TestResult, error) {
+	results, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return f.Apply(results), nil
+}
+
+func (s jsonlStore) Stats(f Filter) (Stats, error) {
+	results, err := s.Query(f)
+	if err != nil {
+		return Stats{}, err
+	}
+	return CalculateStats(results), nil
+}
+
+func (s jsonlStore) PersonalBests(f Filter) ([]TestResult, error) {
+	results, err := s.Query(f)
+	if err != nil {
+		return nil, err
+	}
+	return PersonalBests(results), nil
+}
+
+func (s jsonlStore) Activity(f Filter) ([]Day, error) {
+	results, err := s.Query(f)
+	if err != nil {
+		return nil, err
+	}
+	return Activity(results), nil
+}
+
+// Remove rewrites the file without the result, as lines cannot be removed
+// in place
+func (s jsonlStore) Remove(date time.Time) error {
+	return s.Update(func(results []TestResult) ([]TestResult, error) {
+		return slices.DeleteFunc(results, func(r TestResult) bool {
+			return r.Date.Equal(date)
+		}), nil
+	})
+}
+
+func (s jsonlStore) SetTags(date time.Time, tags []string) error {
+	return s.Update(func(results []TestResult) ([]TestResult, error) {
+		for i := range results {
+			if results[i].Date.Equal(date) {
+				results[i].Tags = tags
+			}
+		}
+		return results, nil
+	})
+}
+
+func (s jsonlStore) ClearKeystrokes(date time.Time) error {
+	return s.Update(func(results []TestResult) ([]TestResult, error) {
+		for i := range results {
+			if results[i].Date.Equal(date) {
+				results[i].Keystrokes = nil
+			}
+		}
+		return results, nil
+	})
+}
+
+func (jsonlStore) Close() error { return nil }
+
+// Remove deletes the result recorded at date
+func Remove(date time.Time) error { return Default().Remove(date) }
+
+// SetTags replaces the tags of the result recorded at date
+func SetTags(date time.Time, tags []string) error { return Default().SetTags(date, tags) }
+
+// ClearKeystrokes drops the keystroke log of the result recorded at date
+func ClearKeystrokes(date time.Time) error { return Default().ClearKeystrokes(date) }
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClearKeystrokes(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := TestResult{Date: date, Mode: "time", Duration: 15, NetWPM: 50, Keystrokes: [][2]int{{0, 1}, {120, 2}}}
+	best := old
+	best.Date = date.Add(time.Minute)
+	best.NetWPM = 60
+
+	for backend, s := range openStores(t) {
+		for _, r := range []TestResult{old, best} {
+			if err := s.Append(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.ClearKeystrokes(old.Date); err != nil {
+			t.Fatal(err)
+		}
+		results, err := s.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Keystrokes != nil || len(results[1].Keystrokes) != 2 {
+			t.Errorf("%s: keystrokes after clearing the first result = %v", backend, results)
+		}
+	}
+}
+
+func TestEditWithinMillisecond(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 100, time.UTC)
+	first := TestResult{Date: date, Mode: "time", Duration: 15, NetWPM: 50}
+	second := first
+	second.Date = date.Add(200 * time.Nanosecond)
+	second.NetWPM = 60
+
+	for backend, s := range openStores(t) {
+		for _, r := range []TestResult{first, second} {
+			if err := s.Append(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SetTags(second.Date, []string{"fast"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Remove(first.Date); err != nil {
+			t.Fatal(err)
+		}
+		results, err := s.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].NetWPM != 60 || len(results[0].Tags) != 1 {
+			t.Errorf("%s: results after tagging the second and removing the first = %+v", backend, results)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package race
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// dialTimeout is how long joining waits for the host to answer
+const dialTimeout = 5 * time.Second
+
+// Client is a player's connection to a race
+type Client struct {
+	ID int // the player's ID in the race
+	c  *conn
+}
+
+// Join connects to the race hosted at addr as name
+func Join(addr, name string) (*Client, error) {
+	nc, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := newConn(nc)
+	if err := c.write(Message{Type: TypeJoin, Version: ProtocolVersion, Name: name}); err != nil {
+		c.close()
+		return nil, err
+	}
+	for {
+		m, err := c.read()
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		switch m.Type {
+		case TypeWelcome:
+			return &Client{ID: m.ID, c: c}, nil
+		case TypeError:
+			c.close()
+			return nil, errors.New(m.Error)
+		}
+	}
+}
+
+// Next waits for the next message from the host. An error message from
+// the host is returned as an error.
+func (c *Client) Next() (Message, error) {
+	m, err := c.c.read()
+	if errors.Is(err, io.EOF) {
+		return m, errors.New("the host ended the race")
+	}
+	if err != nil {
+		return m, err
+	}
+	if m.Type == TypeError {
+		return m, errors.New(m.Error)
+	}
+	return m, nil
+}
+
+// Report sends the player's progress to the host
+func (c *Client) Report(p Player) error {
+	p.ID = c.ID
+	return c.c.write(Message{Type: TypeProgress, Player: &p})
+}
+
+// Close leaves the race
+func (c *Client) Close() error {
+	return c.c.close()
+}
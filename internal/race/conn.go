@@ -0,0 +1,63 @@
+package race
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxLine caps the length of a message, which is mostly the race text
+const maxLine = 1 << 20
+
+// writeTimeout keeps a stalled player from holding up everyone else
+const writeTimeout = 5 * time.Second
+
+// conn reads and writes messages on a network connection. Writes may come
+// from several goroutines.
+type conn struct {
+	c  net.Conn
+	r  *bufio.Scanner
+	mu sync.Mutex
+}
+
+func newConn(c net.Conn) *conn {
+	r := bufio.NewScanner(c)
+	r.Buffer(make([]byte, 4096), maxLine)
+	return &conn{c: c, r: r}
+}
+
+// read returns the next message, or io.EOF once the other end hung up
+func (c *conn) read() (Message, error) {
+	if !c.r.Scan() {
+		if err := c.r.Err(); err != nil {
+			return Message{}, err
+		}
+		return Message{}, io.EOF
+	}
+	var m Message
+	if err := json.Unmarshal(c.r.Bytes(), &m); err != nil {
+		return Message{}, fmt.Errorf("bad message: %w", err)
+	}
+	return m, nil
+}
+
+// write sends m on a line of its own
+func (c *conn) write(m Message) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = c.c.Write(append(line, '\n'))
+	return err
+}
+
+func (c *conn) close() error {
+	return c.c.Close()
+}
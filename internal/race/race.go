@@ -0,0 +1,102 @@
+// Package race runs typing races between taps instances over TCP. One
+// instance hosts a Server that the players, the host included, join with a
+// Client. Every message is a JSON object on its own line.
+//
+// A player sends a join message and gets a welcome with its ID, then lobby
+// messages listing the players until the host starts the race. The start
+// message carries the text, which is typed while progress messages go to
+// the server and update messages listing every player come back. When all
+// players have finished or left, a standings message ranks them.
+package race
+
+import (
+	"cmp"
+	"slices"
+)
+
+// ProtocolVersion is sent when joining, so mismatched versions of taps
+// refuse to race instead of misreading each other
+const ProtocolVersion = 1
+
+// DefaultPort is the port a race is hosted on unless another is given
+const DefaultPort = 7331
+
+// Message types
+const (
+	TypeJoin      = "join"      // player to server: Name, Version
+	TypeWelcome   = "welcome"   // server to player: ID
+	TypeLobby     = "lobby"     // server to players: Players
+	TypeStart     = "start"     // server to players: Race
+	TypeProgress  = "progress"  // player to server: Player
+	TypeUpdate    = "update"    // server to players: Players
+	TypeStandings = "standings" // server to players: Players, ranked
+	TypeError     = "error"     // server to player: Error, then the connection closes
+)
+
+// Message is a line of the protocol
+type Message struct {
+	Type    string   `json:"type"`
+	Version int      `json:"version,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	ID      int      `json:"id,omitempty"`
+	Race    *Race    `json:"race,omitempty"`
+	Player  *Player  `json:"player,omitempty"`
+	Players []Player `json:"players,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Race is the test every player types
+type Race struct {
+	Seed        int64  `json:"seed"` // the seed Text was generated with
+	WordCount   int    `json:"word_count"`
+	Language    string `json:"language"`
+	Punctuation bool   `json:"punctuation"`
+	Numbers     bool   `json:"numbers"`
+	Text        string `json:"text"`
+}
+
+// Player is a racer and how far they got
+type Player struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Progress float64 `json:"progress"` // share of the text typed, 0 to 1
+	WPM      float64 `json:"wpm"`
+	Accuracy float64 `json:"accuracy"`
+	Elapsed  float64 `json:"elapsed"` // seconds since the player started typing
+	Finished bool    `json:"finished"`
+	Failed   bool    `json:"failed,omitempty"` // failed in expert or master difficulty
+	Left     bool    `json:"left,omitempty"`   // disconnected before finishing
+	Place    int     `json:"place,omitempty"`  // set in standings
+}
+
+// done reports whether the player is out of the race
+func (p Player) done() bool {
+	return p.Finished || p.Failed || p.Left
+}
+
+// Rank orders players by how they placed and sets their Place: first those
+// who finished, fastest first, then those still typing or failed by
+// progress, then those who left
+func Rank(players []Player) {
+	group := func(p Player) int {
+		switch {
+		case p.Left:
+			return 2
+		case p.Finished && !p.Failed:
+			return 0
+		}
+		return 1
+	}
+	slices.SortStableFunc(players, func(a, b Player) int {
+		if c := cmp.Compare(group(a), group(b)); c != 0 {
+			return c
+		}
+		if group(a) == 0 {
+			return cmp.Or(cmp.Compare(a.Elapsed, b.Elapsed), cmp.Compare(b.WPM, a.WPM))
+		}
+		return cmp.Compare(b.Progress, a.Progress)
+	})
+	for i := range players {
+		players[i].Place = i + 1
+	}
+}
@@ -0,0 +1,158 @@
+package race
+
+import (
+	"testing"
+	"time"
+)
+
+// host starts a server for r on a free loopback port
+func host(t *testing.T, r Race) *Server {
+	t.Helper()
+	s, err := Listen("127.0.0.1:0", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func join(t *testing.T, s *Server, name string) *Client {
+	t.Helper()
+	c, err := Join(s.Addr().String(), name)
+	if err != nil {
+		t.Fatalf("joining as %s: %v", name, err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// expect reads messages from c until one of type typ arrives
+func expect(t *testing.T, c *Client, typ string) Message {
+	t.Helper()
+	type result struct {
+		m   Message
+		err error
+	}
+	got := make(chan result, 1)
+	go func() {
+		for {
+			m, err := c.Next()
+			if err != nil || m.Type == typ {
+				got <- result{m, err}
+				return
+			}
+		}
+	}()
+	select {
+	case r := <-got:
+		if r.err != nil {
+			t.Fatalf("waiting for %s: %v", typ, r.err)
+		}
+		return r.m
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s message", typ)
+	}
+	return Message{}
+}
+
+func player(t *testing.T, players []Player, id int) Player {
+	t.Helper()
+	for _, p := range players {
+		if p.ID == id {
+			return p
+		}
+	}
+	t.Fatalf("player %d missing from %+v", id, players)
+	return Player{}
+}
+
+func TestRace(t *testing.T) {
+	s := host(t, Race{Seed: 1, WordCount: 2, Language: "english", Text: "one two"})
+
+	alice := join(t, s, "alice")
+	if m := expect(t, alice, TypeLobby); len(m.Players) != 1 || m.Players[0].Name != "alice" {
+		t.Fatalf("lobby after alice joined = %+v", m.Players)
+	}
+	bob := join(t, s, "bob\x1b[2J")
+	for _, c := range []*Client{alice, bob} {
+		m := expect(t, c, TypeLobby)
+		if len(m.Players) != 2 {
+			t.Fatalf("lobby after bob joined = %+v", m.Players)
+		}
+		if name := player(t, m.Players, bob.ID).Name; name != "bob[2J" {
+			t.Errorf("bob's name = %q, want the escape dropped", name)
+		}
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Client{alice, bob} {
+		if m := expect(t, c, TypeStart); m.Race == nil || m.Race.Text != "one two" {
+			t.Fatalf("start = %+v", m.Race)
+		}
+	}
+	if _, err := Join(s.Addr().String(), "carol"); err == nil {
+		t.Error("joining a started race succeeded")
+	}
+
+	if err := alice.Report(Player{Progress: 0.5, WPM: 60}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Client{alice, bob} {
+		m := expect(t, c, TypeUpdate)
+		if p := player(t, m.Players, alice.ID); p.Progress != 0.5 || p.WPM != 60 {
+			t.Errorf("alice in update = %+v", p)
+		}
+	}
+
+	// bob leaves mid-race
+	bob.Close()
+	m := expect(t, alice, TypeUpdate)
+	if p := player(t, m.Players, bob.ID); !p.Left {
+		t.Errorf("bob after leaving = %+v", p)
+	}
+
+	if err := alice.Report(Player{Progress: 1, WPM: 80, Accuracy: 98, Elapsed: 3, Finished: true}); err != nil {
+		t.Fatal(err)
+	}
+	m = expect(t, alice, TypeStandings)
+	if len(m.Players) != 2 || m.Players[0].ID != alice.ID || m.Players[0].Place != 1 {
+		t.Fatalf("standings = %+v", m.Players)
+	}
+	if p := m.Players[1]; p.ID != bob.ID || !p.Left || p.Place != 2 {
+		t.Errorf("bob in standings = %+v", p)
+	}
+}
+
+func TestLeaveLobby(t *testing.T) {
+	s := host(t, Race{Text: "one"})
+	alice := join(t, s, "alice")
+	expect(t, alice, TypeLobby)
+	bob := join(t, s, "")
+	if m := expect(t, alice, TypeLobby); player(t, m.Players, bob.ID).Name != "player 2" {
+		t.Errorf("unnamed player = %+v", m.Players)
+	}
+
+	bob.Close()
+	if m := expect(t, alice, TypeLobby); len(m.Players) != 1 {
+		t.Errorf("lobby after bob left = %+v", m.Players)
+	}
+}
+
+func TestRank(t *testing.T) {
+	players := []Player{
+		{ID: 1, Left: true, Progress: 0.9},
+		{ID: 2, Finished: true, Elapsed: 12},
+		{ID: 3, Progress: 0.4},
+		{ID: 4, Finished: true, Elapsed: 10},
+		{ID: 5, Failed: true, Progress: 0.6},
+	}
+	Rank(players)
+	for i, want := range []int{4, 2, 5, 3, 1} {
+		if players[i].ID != want || players[i].Place != i+1 {
+			t.Errorf("place %d = player %d (place %d), want player %d", i+1, players[i].ID, players[i].Place, want)
+		}
+	}
+}
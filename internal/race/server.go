@@ -0,0 +1,271 @@
+package race
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// joinTimeout is how long a new connection has to send its join message
+const joinTimeout = 10 * time.Second
+
+// sendQueue is how many messages may wait for a slow player before they
+// are dropped from the race
+const sendQueue = 64
+
+// Server hosts a race. Players join until Start, then race until every one
+// of them has finished or left.
+type Server struct {
+	ln   net.Listener
+	race Race
+
+	// mu guards the fields below and is held while queueing broadcasts, so
+	// every player sees the updates in the same order
+	mu      sync.Mutex
+	players []*peer // in the order they joined
+	nextID  int
+	started bool
+	over    bool
+}
+
+// peer is a joined player and their connection. Messages to the player
+// are queued on send and written by their own goroutine, so a stalled
+// player cannot hold up the others.
+type peer struct {
+	conn *conn
+	send chan Message
+	gone bool // disconnected, guarded by Server.mu
+	Player
+}
+
+// writeLoop writes the queued messages until send is closed
+func (p *peer) writeLoop() {
+	for m := range p.send {
+		if err := p.conn.write(m); err != nil {
+			// the read in handle fails too and the player leaves
+			p.conn.close()
+		}
+	}
+}
+
+// Listen hosts r on addr, e.g. ":7331". Players can join once Serve runs.
+func Listen(addr string, r Race) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{ln: ln, race: r}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// JoinAddrs lists the addresses other players can join at: each IPv4
+// address of the machine when listening on all interfaces
+func (s *Server) JoinAddrs() []string {
+	tcp, ok := s.Addr().(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return []string{s.Addr().String()}
+	}
+	port := strconv.Itoa(tcp.Port)
+	var addrs []string
+	ifaces, _ := net.InterfaceAddrs()
+	for _, a := range ifaces {
+		if ip, ok := a.(*net.IPNet); ok && ip.IP.To4() != nil && !ip.IP.IsLoopback() {
+			addrs = append(addrs, net.JoinHostPort(ip.IP.String(), port))
+		}
+	}
+	if len(addrs) == 0 {
+		addrs = append(addrs, net.JoinHostPort("127.0.0.1", port))
+	}
+	return addrs
+}
+
+// Serve accepts players until Close
+func (s *Server) Serve() error {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(newConn(c))
+	}
+}
+
+// Close stops the server and disconnects every player
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.players {
+		p.conn.close()
+	}
+	return err
+}
+
+// Start sends the race to every player who joined
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("the race has already started")
+	}
+	s.started = true
+	s.broadcast(Message{Type: TypeStart, Race: &s.race})
+	return nil
+}
+
+// handle serves one player's connection
+func (s *Server) handle(c *conn) {
+	defer c.close()
+
+	c.c.SetReadDeadline(time.Now().Add(joinTimeout))
+	m, err := c.read()
+	if err != nil || m.Type != TypeJoin {
+		return
+	}
+	c.c.SetReadDeadline(time.Time{})
+	if m.Version != ProtocolVersion {
+		c.write(Message{Type: TypeError, Error: fmt.Sprintf("the host runs race protocol %d, this taps speaks %d; update taps to match", ProtocolVersion, m.Version)})
+		return
+	}
+	p, err := s.join(c, m.Name)
+	if err != nil {
+		c.write(Message{Type: TypeError, Error: err.Error()})
+		return
+	}
+	defer s.leave(p)
+
+	for {
+		m, err := c.read()
+		if err != nil {
+			return
+		}
+		if m.Type == TypeProgress && m.Player != nil {
+			s.progress(p, *m.Player)
+		}
+	}
+}
+
+func (s *Server) join(c *conn, name string) (*peer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil, errors.New("the race has already started")
+	}
+	s.nextID++
+	name = printable(name)
+	if name == "" {
+		name = fmt.Sprintf("player %d", s.nextID)
+	}
+	p := &peer{conn: c, send: make(chan Message, sendQueue), Player: Player{ID: s.nextID, Name: name}}
+	go p.writeLoop()
+	s.players = append(s.players, p)
+	p.send <- Message{Type: TypeWelcome, ID: p.ID}
+	s.broadcast(Message{Type: TypeLobby, Players: s.snapshot()})
+	return p, nil
+}
+
+// printable drops control characters from a name, so it cannot carry
+// escape sequences to the other players' terminals
+func printable(name string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+}
+
+// leave drops a player who disconnected: from the lobby before the race,
+// or marked as having left during it
+func (s *Server) leave(p *peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// broadcast skips the player from here on
+	p.gone = true
+	defer close(p.send)
+	if !s.started {
+		for i, q := range s.players {
+			if q == p {
+				s.players = append(s.players[:i], s.players[i+1:]...)
+				break
+			}
+		}
+		s.broadcast(Message{Type: TypeLobby, Players: s.snapshot()})
+		return
+	}
+	if p.done() {
+		return
+	}
+	p.Left = true
+	s.update()
+}
+
+// progress records a player's progress report
+func (s *Server) progress(p *peer, report Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started || p.done() {
+		return
+	}
+	p.Progress = min(max(report.Progress, 0), 1)
+	p.WPM = report.WPM
+	p.Accuracy = report.Accuracy
+	p.Elapsed = report.Elapsed
+	p.Finished = report.Finished
+	p.Failed = report.Failed
+	s.update()
+}
+
+// update sends every player's progress, followed by the standings once
+// the last player is done. s.mu must be held.
+func (s *Server) update() {
+	if s.over {
+		return
+	}
+	s.broadcast(Message{Type: TypeUpdate, Players: s.snapshot()})
+	for _, p := range s.players {
+		if !p.done() {
+			return
+		}
+	}
+	s.over = true
+	standings := s.snapshot()
+	Rank(standings)
+	s.broadcast(Message{Type: TypeStandings, Players: standings})
+}
+
+// snapshot copies the players. s.mu must be held.
+func (s *Server) snapshot() []Player {
+	players := make([]Player, len(s.players))
+	for i, p := range s.players {
+		players[i] = p.Player
+	}
+	return players
+}
+
+// broadcast queues m for every player still connected, disconnecting any
+// whose queue is full. s.mu must be held.
+func (s *Server) broadcast(m Message) {
+	for _, p := range s.players {
+		if p.gone {
+			continue
+		}
+		select {
+		case p.send <- m:
+		default:
+			p.conn.close()
+		}
+	}
+}
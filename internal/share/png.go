@@ -0,0 +1,116 @@
+package share
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+// canvas draws the PNG card
+type canvas struct {
+	img *image.RGBA
+}
+
+func writePNG(w io.Writer, c Card) error {
+	t := c.Theme
+	cv := canvas{image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))}
+	cv.fill(0, 0, cardWidth, cardHeight, rgba(t.Background))
+
+	sub, fg, main := rgba(t.Sub), rgba(t.Foreground), rgba(t.Main)
+	cv.text(cardPad, cardPad, 3, main, "taps")
+	date := c.Date.Format("Jan 2 2006 15:04")
+	cv.text(cardWidth-cardPad-textWidth(date, 2), cardPad+4, 2, sub, date)
+
+	wpm := fmt.Sprintf("%.0f", c.NetWPM)
+	cv.text(cardPad, 80, 12, main, wpm)
+	cv.text(cardPad+textWidth(wpm, 12)+16, 80+12*glyphHeight-4*glyphHeight, 4, sub, "wpm")
+
+	x := cardPad
+	for _, s := range c.stats() {
+		cv.text(x, 190, 3, sub, s[0])
+		x += textWidth(s[0], 3) + 12
+		cv.text(x, 190, 3, fg, s[1])
+		x += textWidth(s[1], 3) + 36
+	}
+
+	if len(c.Net) > 1 {
+		cv.fill(cardPad, graphTop+graphHeight, cardWidth-cardPad, graphTop+graphHeight+1, sub)
+		lines := graphPoints(c.Raw, c.Net)
+		for i, col := range []color.RGBA{rgba(theme.Blend(t.Sub, t.Foreground, 0.5)), main} {
+			for j := 1; j < len(lines[i]); j++ {
+				cv.line(lines[i][j-1], lines[i][j], col)
+			}
+		}
+	}
+
+	cv.text(cardPad, cardHeight-cardPad-2*glyphHeight, 2, sub, c.Config)
+	return png.Encode(w, cv.img)
+}
+
+// rgba converts a theme color, falling back to white
+func rgba(c lipgloss.Color) color.RGBA {
+	col, err := colorful.Hex(string(c))
+	if err != nil {
+		return color.RGBA{255, 255, 255, 255}
+	}
+	r, g, b := col.RGB255()
+	return color.RGBA{r, g, b, 255}
+}
+
+// fill paints the rectangle from x0, y0 up to x1, y1
+func (cv canvas) fill(x0, y0, x1, y1 int, col color.RGBA) {
+	r := image.Rect(x0, y0, x1, y1).Intersect(cv.img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			cv.img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+// text draws s with its top left corner at x, y, each font pixel scaled to
+// a square of scale pixels
+func (cv canvas) text(x, y, scale int, col color.RGBA, s string) {
+	for _, r := range s {
+		g, ok := glyphs[unicode.ToUpper(r)]
+		if !ok {
+			g = glyphs['?']
+		}
+		for row, bits := range g {
+			for bit := 0; bit < glyphWidth; bit++ {
+				if bits&(1<<(glyphWidth-1-bit)) != 0 {
+					px, py := x+bit*scale, y+row*scale
+					cv.fill(px, py, px+scale, py+scale, col)
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
+
+// textWidth returns the width of s drawn at scale
+func textWidth(s string, scale int) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// line draws a three pixel wide line from a to b
+func (cv canvas) line(a, b point, col color.RGBA) {
+	steps := int(math.Max(math.Abs(b.x-a.x), math.Abs(b.y-a.y))) + 1
+	for i := 0; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		x := int(math.Round(a.x + (b.x-a.x)*f))
+		y := int(math.Round(a.y + (b.y-a.y)*f))
+		cv.fill(x-1, y-1, x+2, y+2, col)
+	}
+}
@@ -0,0 +1,184 @@
+// Package share renders test results for posting elsewhere: as PNG or SVG
+// cards and as a short markdown snippet for the clipboard.
+package share
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+// Card is a test result as shown on a share card
+type Card struct {
+	NetWPM      float64
+	RawWPM      float64
+	Accuracy    float64
+	Consistency float64
+	Config      string // the test config, e.g. "time | 30s | english"
+	Date        time.Time
+	// Net is the net WPM so far at each second of the test, and Raw the raw
+	// WPM of the keys typed within each second
+	Net []float64
+	Raw []float64
+	// Theme colors the card
+	Theme *theme.Theme
+}
+
+// Image formats a card can be saved in
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+// Write renders the card in format to w
+func Write(w io.Writer, c Card, format Format) error {
+	switch format {
+	case FormatPNG:
+		return writePNG(w, c)
+	case FormatSVG:
+		return writeSVG(w, c)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Save writes the card to a new timestamped file in the taps exports
+// directory and returns its path
+func Save(c Card, format Format) (string, error) {
+	name := fmt.Sprintf("result-%s.%s", c.Date.Format("20060102-150405"), format)
+	p, err := xdg.DataFile(filepath.Join("taps", "exports", name))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return "", err
+	}
+	if err := Write(f, c, format); err != nil {
+		f.Close()
+		return "", err
+	}
+	return p, f.Close()
+}
+
+// Text returns the card as a compact markdown snippet
+func Text(c Card) string {
+	return fmt.Sprintf("**%.0f wpm** · %.1f%% acc · %.1f%% consistency · %.0f raw\n`%s` %s",
+		c.NetWPM, c.Accuracy, c.Consistency, c.RawWPM, c.Config, sparkline(c.Net))
+}
+
+// Copy puts text on the clipboard of the terminal behind w with an OSC 52
+// escape sequence, which also works over SSH. Inside tmux or screen the
+// sequence is passed through to the outer terminal.
+func Copy(w io.Writer, text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(w)
+	return err
+}
+
+// sparklineWidth caps the characters of a sparkline
+const sparklineWidth = 30
+
+// sparkline draws values as a line of block characters, averaging
+// neighbouring values of long series
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values) > sparklineWidth {
+		avg := make([]float64, sparklineWidth)
+		for i := range avg {
+			part := values[i*len(values)/sparklineWidth : (i+1)*len(values)/sparklineWidth]
+			for _, v := range part {
+				avg[i] += v / float64(len(part))
+			}
+		}
+		values = avg
+	}
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	lo, hi := bounds(values)
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v - lo) / (hi - lo) * float64(len(blocks)-1))
+		}
+		b.WriteRune(blocks[i])
+	}
+	return b.String()
+}
+
+// bounds returns the smallest and largest of values
+func bounds(values ...[]float64) (lo, hi float64) {
+	first := true
+	for _, vs := range values {
+		for _, v := range vs {
+			if first || v < lo {
+				lo = v
+			}
+			if first || v > hi {
+				hi = v
+			}
+			first = false
+		}
+	}
+	return lo, hi
+}
+
+// card layout, in pixels
+const (
+	cardWidth   = 800
+	cardHeight  = 400
+	cardPad     = 40
+	graphTop    = 230
+	graphHeight = 100
+)
+
+type point struct{ x, y float64 }
+
+// graphPoints maps each series onto the graph area of the card, on a
+// common scale starting at zero
+func graphPoints(series ...[]float64) [][]point {
+	_, hi := bounds(series...)
+	if hi <= 0 {
+		hi = 1
+	}
+	out := make([][]point, len(series))
+	for s, values := range series {
+		for i, v := range values {
+			x := float64(cardPad)
+			if len(values) > 1 {
+				x += float64(i) * float64(cardWidth-2*cardPad) / float64(len(values)-1)
+			}
+			y := float64(graphTop+graphHeight) - v/hi*graphHeight
+			out[s] = append(out[s], point{x, y})
+		}
+	}
+	return out
+}
+
+// stats lists the label and value pairs shown under the WPM
+func (c Card) stats() [][2]string {
+	return [][2]string{
+		{"acc", fmt.Sprintf("%.1f%%", c.Accuracy)},
+		{"consistency", fmt.Sprintf("%.1f%%", c.Consistency)},
+		{"raw", fmt.Sprintf("%.0f", c.RawWPM)},
+	}
+}
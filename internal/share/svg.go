@@ -0,0 +1,58 @@
+package share
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+func writeSVG(w io.Writer, c Card) error {
+	t := c.Theme
+	rawColor := theme.Blend(t.Sub, t.Foreground, 0.5)
+	var b strings.Builder
+	text := func(x, y, size int, color, anchor, s string) {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="%d" fill="%s" text-anchor="%s">%s</text>`+"\n",
+			x, y, size, color, anchor, html.EscapeString(s))
+	}
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace">`+"\n",
+		cardWidth, cardHeight, cardWidth, cardHeight)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" rx="16" fill="%s"/>`+"\n", t.Background)
+
+	text(cardPad, cardPad+16, 20, string(t.Main), "start", "taps")
+	text(cardWidth-cardPad, cardPad+16, 16, string(t.Sub), "end", c.Date.Format("Jan 2 2006 15:04"))
+
+	wpm := fmt.Sprintf("%.0f", c.NetWPM)
+	text(cardPad, 164, 96, string(t.Main), "start", wpm)
+	text(cardPad+len(wpm)*58+12, 164, 28, string(t.Sub), "start", "wpm")
+
+	x := cardPad
+	for _, s := range c.stats() {
+		fmt.Fprintf(&b, `<text x="%d" y="206" font-size="20"><tspan fill="%s">%s </tspan><tspan fill="%s" font-weight="bold">%s</tspan></text>`+"\n",
+			x, t.Sub, s[0], t.Foreground, s[1])
+		x += (len(s[0])+len(s[1])+4)*12 + 8
+	}
+
+	if len(c.Net) > 1 {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="1"/>`+"\n",
+			cardPad, graphTop+graphHeight, cardWidth-cardPad, graphTop+graphHeight, t.Sub)
+		lines := graphPoints(c.Raw, c.Net)
+		for i, color := range []string{string(rawColor), string(t.Main)} {
+			var pts []string
+			for _, p := range lines[i] {
+				pts = append(pts, fmt.Sprintf("%.1f,%.1f", p.x, p.y))
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="3" stroke-linejoin="round"/>`+"\n",
+				strings.Join(pts, " "), color)
+		}
+	}
+
+	text(cardPad, cardHeight-cardPad+4, 18, string(t.Sub), "start", c.Config)
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
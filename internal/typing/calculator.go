@@ -2,6 +2,21 @@ package typing
 
 import "math"
 
+// Metrics profiles, selecting how an Engine defines its results
+const (
+	// ProfileTaps counts every correct character towards net WPM, every
+	// keystroke towards raw WPM and judges accuracy on the final text
+	ProfileTaps = "taps"
+	// ProfileMonkeytype follows Monkeytype: net WPM counts only correctly
+	// typed words, raw WPM the characters left in the text, accuracy every
+	// keypress as it was typed and consistency maps the variation of each
+	// second's raw WPM through Monkeytype's curve
+	ProfileMonkeytype = "monkeytype"
+)
+
+// Profiles lists the metrics profiles
+var Profiles = []string{ProfileTaps, ProfileMonkeytype}
+
 // RawWPM calculates raw words per minute: (totalKeystrokes / 5) / minutes
 func RawWPM(totalKeystrokes int, elapsedSeconds float64) float64 {
 	if elapsedSeconds <= 0 {
@@ -66,3 +81,31 @@ func Consistency(perSecondWPM []float64) float64 {
 	}
 	return consistency
 }
+
+// KogasaConsistency scores per-second raw WPM samples the way Monkeytype
+// does, mapping their coefficient of variation onto 0 to 100 with a tanh
+// curve instead of subtracting it
+func KogasaConsistency(perSecondRaw []float64) float64 {
+	if len(perSecondRaw) < 2 {
+		return 100
+	}
+
+	mean := 0.0
+	for _, v := range perSecondRaw {
+		mean += v
+	}
+	mean /= float64(len(perSecondRaw))
+	if mean == 0 {
+		return 0
+	}
+
+	variance := 0.0
+	for _, v := range perSecondRaw {
+		diff := v - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(perSecondRaw))
+
+	cv := math.Sqrt(variance) / mean
+	return 100 * (1 - math.Tanh(cv+math.Pow(cv, 3)/3+math.Pow(cv, 5)/5))
+}
@@ -28,9 +28,11 @@ type Engine struct {
 	CursorPos      int
 	CurrentWord    int
 	StartTime      time.Time
+	EndTime        time.Time // set when the test finishes or fails
+	TimeLimit      time.Duration // length of a time test, 0 for other modes
 	Started        bool
 	Finished       bool
-	PerSecondWPM   []float64
+	PerSecondWPM   []float64 // raw WPM so far, sampled every second
 	TotalTyped     int
 	CorrectChars   int
 	IncorrectChars int
@@ -49,6 +51,55 @@ type Engine struct {
 	// Track failed state for expert/master
 	Failed       bool
 	FailedReason string
+
+	// AFK detection: a gap of IdleTimeout or more between keystrokes is an
+	// idle period. With PauseWhenIdle the clock stops for it, otherwise the
+	// test is marked AFK.
+	IdleTimeout   time.Duration // 0 turns detection off
+	PauseWhenIdle bool
+	AFK           bool    // went idle without pausing
+	Paused        bool    // the clock is stopped until the next keystroke
+	IdleSeconds   float64 // total length of the idle periods
+	lastKeyTime   time.Time
+	pausedAt      time.Time
+	pausedTotal   time.Duration
+
+	// Held is set while paused with Pause; keystrokes are ignored until
+	// Resume
+	Held          bool
+	PausedSeconds float64 // total time held
+	heldAt        time.Time
+	// MetricsProfile selects how WPM, accuracy and consistency are defined,
+	// see ProfileTaps and ProfileMonkeytype
+	MetricsProfile string
+	// keypresses judged when they were typed, whatever happened to them
+	// later, and the keys typed in each second of the test
+	KeysCorrect   int
+	KeysIncorrect int
+	KeysPerSecond []int
+	// ErrorsPerSecond counts the incorrect keys typed in each second
+	ErrorsPerSecond []int
+	// PerSecondNet is the net WPM so far, sampled with PerSecondWPM
+	PerSecondNet []float64
+
+	// WordBurst is the burst WPM of each ended word, indexed like Words:
+	// the keys typed for the word over the time since the previous one
+	// ended. Words not ended yet are 0.
+	WordBurst   []float64
+	bursts      []burst // word ends in the order they happened
+	wordKeys    int     // keys typed since the previous word ended
+	lastWordEnd time.Duration
+
+	// Keystrokes logs every key handled, for replaying the test as a ghost
+	Keystrokes []Keystroke
+	// Ghost is the pace caret shown alongside the cursor, if any
+	Ghost *Ghost
+}
+
+// burst is a word's burst WPM and when in the test it ended
+type burst struct {
+	at  time.Duration
+	wpm float64
 }
 
 func NewEngine(target string, stopOnError string, freedomMode bool, difficulty string) *Engine {
@@ -84,19 +135,37 @@ func NewEngine(target string, stopOnError string, freedomMode bool, difficulty s
 }
 
 func (e *Engine) HandleKey(key rune) {
-	if e.Finished || e.Failed {
+	if e.Finished || e.Failed || e.Held {
 		return
 	}
+	now := time.Now()
 	if !e.Started {
 		e.Started = true
-		e.StartTime = time.Now()
+		e.StartTime = now
 		e.lastSampleTime = e.StartTime
+		e.lastKeyTime = now
+	}
+	// a key pressed after the time ran out ends the test instead
+	if e.TimeLimit > 0 && e.elapsed(now) >= e.TimeLimit {
+		e.Finish()
+		return
 	}
+	e.touch(now)
+	defer e.logKey(now)
+	defer e.stopClock(now)
 
 	e.TotalTyped++
+	e.wordKeys++
+	second := int(e.elapsed(now) / time.Second)
+	e.KeysPerSecond = countAt(e.KeysPerSecond, second, 1)
+	incorrect := e.KeysIncorrect
+	defer func() {
+		e.ErrorsPerSecond = countAt(e.ErrorsPerSecond, second, e.KeysIncorrect-incorrect)
+	}()
 
 	if e.CursorPos >= len(e.Chars) {
 		// We've gone past all characters - add as extra to last word
+		e.KeysIncorrect++
 		e.ExtraChars++
 		e.ExtraByWord[e.CurrentWord] = append(e.ExtraByWord[e.CurrentWord], DisplayChar{
 			Typed: key,
@@ -110,6 +179,11 @@ func (e *Engine) HandleKey(key rune) {
 	if expected == ' ' {
 		// Space pressed - move to next word
 		if key == ' ' {
+			if e.wordCorrect(e.CurrentWord) {
+				e.KeysCorrect++
+			} else {
+				e.KeysIncorrect++
+			}
 			// Mark any remaining chars in current word as missed
 			if e.CurrentWord < len(e.Words) {
 				end := e.wordEndIdx[e.CurrentWord]
@@ -124,9 +198,11 @@ func (e *Engine) HandleKey(key rune) {
 			e.Chars[e.CursorPos].Typed = key
 			e.CorrectChars++
 			e.CursorPos++
+			e.endWord(e.CurrentWord, now)
 			e.CurrentWord++
 		} else {
 			// Typed a non-space where space expected - add as extra char
+			e.KeysIncorrect++
 			e.ExtraChars++
 			e.ExtraByWord[e.CurrentWord] = append(e.ExtraByWord[e.CurrentWord], DisplayChar{
 				Typed: key,
@@ -140,6 +216,7 @@ func (e *Engine) HandleKey(key rune) {
 	} else if key == ' ' {
 		// Space pressed but not expected - skip to next word
 		// Mark remaining chars as missed
+		e.KeysIncorrect++
 		if e.CurrentWord < len(e.Words) {
 			end := e.wordEndIdx[e.CurrentWord]
 			for i := e.CursorPos; i < end && i < len(e.Chars); i++ {
@@ -157,6 +234,7 @@ func (e *Engine) HandleKey(key rune) {
 				e.CursorPos = spaceIdx + 1
 			}
 		}
+		e.endWord(e.CurrentWord, now)
 		e.CurrentWord++
 		if e.Difficulty == "expert" {
 			// Check if any chars in the word we just left were incorrect
@@ -176,12 +254,14 @@ func (e *Engine) HandleKey(key rune) {
 			}
 		}
 	} else if key == expected {
+		e.KeysCorrect++
 		e.Chars[e.CursorPos].State = CharCorrect
 		e.Chars[e.CursorPos].Typed = key
 		e.CorrectChars++
 		e.CursorPos++
 	} else {
 		// Wrong character
+		e.KeysIncorrect++
 		if e.StopOnError == "letter" {
 			// Don't advance cursor
 			return
@@ -200,14 +280,18 @@ func (e *Engine) HandleKey(key rune) {
 
 	// Check if test is finished (word mode / quote mode)
 	if e.CursorPos >= len(e.Chars) {
+		e.endWord(e.CurrentWord, now)
 		e.Finished = true
 	}
 }
 
 func (e *Engine) HandleBackspace() {
-	if e.Finished || e.Failed || !e.Started {
+	if e.Finished || e.Failed || e.Held || !e.Started {
 		return
 	}
+	now := time.Now()
+	e.touch(now)
+	defer e.logKey(now)
 
 	// Check for extra chars in current word first
 	if extras, ok := e.ExtraByWord[e.CurrentWord]; ok && len(extras) > 0 {
@@ -255,9 +339,12 @@ func (e *Engine) HandleBackspace() {
 }
 
 func (e *Engine) HandleCtrlBackspace() {
-	if e.Finished || e.Failed || !e.Started {
+	if e.Finished || e.Failed || e.Held || !e.Started {
 		return
 	}
+	now := time.Now()
+	e.touch(now)
+	defer e.logKey(now)
 
 	// Delete entire current word progress
 	if e.CurrentWord < len(e.wordStartIdx) {
@@ -282,51 +369,270 @@ func (e *Engine) HandleCtrlBackspace() {
 	}
 }
 
+// touch records keyboard activity at now, ending an idle period
+func (e *Engine) touch(now time.Time) {
+	if gap := now.Sub(e.lastKeyTime); e.IdleTimeout > 0 && gap >= e.IdleTimeout {
+		e.IdleSeconds += gap.Seconds()
+	}
+	if e.Paused {
+		e.pausedTotal += now.Sub(e.pausedAt)
+		e.Paused = false
+	}
+	e.lastKeyTime = now
+}
+
+// CheckIdle looks for an idle period in progress at now. The clock is
+// paused from the last keystroke when PauseWhenIdle is set, dropping the
+// samples taken since; otherwise the test is marked AFK. It reports whether
+// the test went idle with this call.
+func (e *Engine) CheckIdle(now time.Time) bool {
+	if !e.Started || e.Finished || e.Failed || e.Paused || e.IdleTimeout <= 0 {
+		return false
+	}
+	if now.Sub(e.lastKeyTime) < e.IdleTimeout {
+		return false
+	}
+	if !e.PauseWhenIdle {
+		wasAFK := e.AFK
+		e.AFK = true
+		return !wasAFK
+	}
+	e.Paused = true
+	e.pausedAt = e.lastKeyTime
+	if n := int(e.ElapsedSeconds()); n < len(e.PerSecondWPM) {
+		e.PerSecondWPM = e.PerSecondWPM[:n]
+		e.PerSecondNet = e.PerSecondNet[:n]
+	}
+	return true
+}
+
+// Pause stops the clock and sampling of a running test until Resume
+func (e *Engine) Pause() {
+	if !e.Started || e.Finished || e.Failed || e.Held {
+		return
+	}
+	now := time.Now()
+	if e.Paused {
+		// already paused while idle: the idle period ends here
+		e.IdleSeconds += now.Sub(e.lastKeyTime).Seconds()
+	} else {
+		e.Paused = true
+		e.pausedAt = now
+	}
+	e.Held = true
+	e.heldAt = now
+}
+
+// Resume restarts the clock of a test stopped with Pause
+func (e *Engine) Resume() {
+	if !e.Held {
+		return
+	}
+	now := time.Now()
+	e.PausedSeconds += now.Sub(e.heldAt).Seconds()
+	e.Held = false
+	// time held is not idle time
+	e.lastKeyTime = now
+	e.touch(now)
+}
+
 func (e *Engine) SampleWPM() {
-	if !e.Started || e.Finished {
+	if !e.Started || e.Finished || e.Paused {
 		return
 	}
-	elapsed := time.Since(e.StartTime).Seconds()
+	elapsed := e.ElapsedSeconds()
 	if elapsed <= 0 {
 		return
 	}
 	raw := RawWPM(e.TotalTyped, elapsed)
 	e.PerSecondWPM = append(e.PerSecondWPM, raw)
+	e.PerSecondNet = append(e.PerSecondNet, e.CurrentNetWPM())
 	e.lastSampleTime = time.Now()
 }
 
-func (e *Engine) ElapsedSeconds() float64 {
+// countAt adds n to counts[i], growing counts as needed
+func countAt(counts []int, i, n int) []int {
+	for len(counts) <= i {
+		counts = append(counts, 0)
+	}
+	counts[i] += n
+	return counts
+}
+
+// stopClock records now as the end of a test that just finished or failed
+func (e *Engine) stopClock(now time.Time) {
+	if (e.Finished || e.Failed) && e.EndTime.IsZero() {
+		e.EndTime = now
+	}
+}
+
+// elapsed returns the time the clock ran until now, excluding pauses. It
+// stops at EndTime once the test is over.
+func (e *Engine) elapsed(now time.Time) time.Duration {
 	if !e.Started {
 		return 0
 	}
-	if e.Finished {
-		// Use last sample time as approximate end
-		return time.Since(e.StartTime).Seconds()
+	switch {
+	case !e.EndTime.IsZero():
+		now = e.EndTime
+	case e.Paused:
+		now = e.pausedAt
 	}
-	return time.Since(e.StartTime).Seconds()
+	return now.Sub(e.StartTime) - e.pausedTotal
+}
+
+func (e *Engine) ElapsedSeconds() float64 {
+	return e.elapsed(time.Now()).Seconds()
+}
+
+// Remaining returns the time left of a test with a TimeLimit
+func (e *Engine) Remaining() time.Duration {
+	return max(e.TimeLimit-e.elapsed(time.Now()), 0)
 }
 
 func (e *Engine) CurrentRawWPM() float64 {
+	if e.MetricsProfile == ProfileMonkeytype {
+		// every character still in the text, without backspaced ones
+		return RawWPM(e.CorrectChars+e.IncorrectChars+e.ExtraChars, e.ElapsedSeconds())
+	}
 	return RawWPM(e.TotalTyped, e.ElapsedSeconds())
 }
 
 func (e *Engine) CurrentNetWPM() float64 {
+	if e.MetricsProfile == ProfileMonkeytype {
+		return NetWPM(e.correctWordChars(), e.ElapsedSeconds())
+	}
 	return NetWPM(e.CorrectChars, e.ElapsedSeconds())
 }
 
 func (e *Engine) CurrentAccuracy() float64 {
+	if e.MetricsProfile == ProfileMonkeytype {
+		return Accuracy(e.KeysCorrect, e.KeysIncorrect, 0)
+	}
 	return Accuracy(e.CorrectChars, e.IncorrectChars, e.ExtraChars)
 }
 
 func (e *Engine) CurrentConsistency() float64 {
+	if e.MetricsProfile == ProfileMonkeytype {
+		return KogasaConsistency(e.RawPerSecond())
+	}
 	return Consistency(e.PerSecondWPM)
 }
 
+// endWord records the burst WPM of word w, ended at now
+func (e *Engine) endWord(w int, now time.Time) {
+	if w >= len(e.Words) {
+		return
+	}
+	at := e.elapsed(now)
+	keys := e.wordKeys
+	if len(e.bursts) == 0 {
+		keys-- // the first key starts the clock
+	}
+	wpm := RawWPM(keys, (at - e.lastWordEnd).Seconds())
+	for len(e.WordBurst) <= w {
+		e.WordBurst = append(e.WordBurst, 0)
+	}
+	e.WordBurst[w] = wpm
+	e.bursts = append(e.bursts, burst{at: at, wpm: wpm})
+	e.lastWordEnd = at
+	e.wordKeys = 0
+}
+
+// BurstPerSecond returns for each second sampled in PerSecondWPM the burst
+// WPM of the last word ended by then, to plot alongside it
+func (e *Engine) BurstPerSecond() []float64 {
+	out := make([]float64, len(e.PerSecondWPM))
+	next, last := 0, 0.0
+	for i := range out {
+		t := time.Duration(i+1) * time.Second
+		for next < len(e.bursts) && e.bursts[next].at <= t {
+			last = e.bursts[next].wpm
+			next++
+		}
+		out[i] = last
+	}
+	return out
+}
+
+// wordCorrect reports whether word w is typed in full without mistakes
+func (e *Engine) wordCorrect(w int) bool {
+	if w >= len(e.Words) || len(e.ExtraByWord[w]) > 0 {
+		return false
+	}
+	for i := e.wordStartIdx[w]; i < e.wordEndIdx[w]; i++ {
+		if e.Chars[i].State != CharCorrect {
+			return false
+		}
+	}
+	return true
+}
+
+// correctWordChars counts the characters of correctly typed words and the
+// spaces after them, including the word being typed if it is correct so
+// far, as Monkeytype does for net WPM
+func (e *Engine) correctWordChars() int {
+	n := 0
+	for w := 0; w <= e.CurrentWord && w < len(e.Words); w++ {
+		if w < e.CurrentWord {
+			if e.wordCorrect(w) {
+				n += e.wordEndIdx[w] - e.wordStartIdx[w] + 1
+			}
+			continue
+		}
+		start, end := e.wordStartIdx[w], min(e.CursorPos, e.wordEndIdx[w])
+		if len(e.ExtraByWord[w]) > 0 {
+			continue
+		}
+		for i := start; i < end; i++ {
+			if e.Chars[i].State != CharCorrect {
+				end = start
+				break
+			}
+		}
+		n += max(end-start, 0)
+	}
+	return n
+}
+
+// RawPerSecond returns the raw WPM of each second of the test from the keys
+// typed in it, unlike the running average in PerSecondWPM. The last,
+// partial second is included if at least half of it has passed.
+func (e *Engine) RawPerSecond() []float64 {
+	elapsed := e.ElapsedSeconds()
+	whole := int(elapsed)
+	keys := func(i int) int {
+		if i < len(e.KeysPerSecond) {
+			return e.KeysPerSecond[i]
+		}
+		return 0
+	}
+	raw := make([]float64, 0, whole+1)
+	for i := 0; i < whole; i++ {
+		raw = append(raw, RawWPM(keys(i), 1))
+	}
+	if rest := elapsed - float64(whole); rest >= 0.5 {
+		raw = append(raw, RawWPM(keys(whole), rest))
+	}
+	return raw
+}
+
 func (e *Engine) Finish() {
 	if e.Finished {
 		return
 	}
+	// an idle period running into the end of the test counts as well
+	now := time.Now()
+	e.CheckIdle(now)
+	if e.Started {
+		e.touch(now)
+	}
+	// a time test ends exactly at its limit, however late this is called
+	if e.TimeLimit > 0 && e.elapsed(now) > e.TimeLimit {
+		now = e.StartTime.Add(e.pausedTotal + e.TimeLimit)
+	}
 	e.Finished = true
+	e.stopClock(now)
 	// Count remaining untyped as missed
 	for i := e.CursorPos; i < len(e.Chars); i++ {
 		if e.Chars[i].State == CharUntyped {
@@ -0,0 +1,51 @@
+package typing
+
+import (
+	"sort"
+	"time"
+)
+
+// Keystroke is a key handled by an Engine: when in the test it was pressed
+// and the cursor position it left
+type Keystroke struct {
+	At  time.Duration
+	Pos int
+}
+
+// Ghost is a pace caret, moving through the text alongside the typist
+type Ghost struct {
+	// Label names the pace in the results, e.g. "pb" or "100 wpm"
+	Label string
+	// WPM is the steady pace of the caret, and the speed to beat. For a
+	// replay it is the net WPM of the replayed test.
+	WPM float64
+	// Replay moves the caret as the keystrokes of an earlier test did
+	// instead of at a steady pace
+	Replay []Keystroke
+}
+
+// Pos returns the character the ghost is at after elapsed
+func (g *Ghost) Pos(elapsed time.Duration) int {
+	if len(g.Replay) > 0 {
+		i := sort.Search(len(g.Replay), func(i int) bool { return g.Replay[i].At > elapsed })
+		if i == 0 {
+			return 0
+		}
+		return g.Replay[i-1].Pos
+	}
+	return int(elapsed.Seconds() * g.WPM * 5 / 60)
+}
+
+// logKey records the cursor position left by a key handled at now
+func (e *Engine) logKey(now time.Time) {
+	e.Keystrokes = append(e.Keystrokes, Keystroke{At: e.elapsed(now), Pos: e.CursorPos})
+}
+
+// GhostPos returns the character the ghost caret is at, or -1 without a
+// ghost
+func (e *Engine) GhostPos() int {
+	if e.Ghost == nil {
+		return -1
+	}
+	return min(e.Ghost.Pos(e.elapsed(time.Now())), len(e.Chars))
+}
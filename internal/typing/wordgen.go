@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/meszmate/taps/internal/words"
 )
@@ -21,12 +22,25 @@ var (
 	quotes         []Quote
 )
 
+// rng drives word and quote selection so a seeded session is reproducible
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// Seed makes subsequent word and quote selection deterministic
+func Seed(seed int64) {
+	rng = rand.New(rand.NewSource(seed))
+}
+
 func init() {
 	_ = json.Unmarshal(words.EnglishJSON, &englishWords)
 	_ = json.Unmarshal(words.English1kJSON, &english1kWords)
 	_ = json.Unmarshal(words.QuotesJSON, &quotes)
 }
 
+// Languages returns the names of the available word lists
+func Languages() []string {
+	return []string{"english", "english_1k"}
+}
+
 func GetWordList(language string) []string {
 	switch language {
 	case "english_1k":
@@ -46,16 +60,16 @@ func GenerateWords(count int, language string, addPunctuation, addNumbers bool)
 
 	result := make([]string, 0, count)
 	for i := 0; i < count; i++ {
-		if addNumbers && rand.Float64() < 0.1 {
-			result = append(result, fmt.Sprintf("%d", rand.Intn(100)))
+		if addNumbers && rng.Float64() < 0.1 {
+			result = append(result, fmt.Sprintf("%d", rng.Intn(100)))
 			continue
 		}
 
-		word := wordList[rand.Intn(len(wordList))]
+		word := wordList[rng.Intn(len(wordList))]
 
-		if addPunctuation && rand.Float64() < 0.15 {
-			p := punctuationMarks[rand.Intn(len(punctuationMarks))]
-			if rand.Float64() < 0.5 {
+		if addPunctuation && rng.Float64() < 0.15 {
+			p := punctuationMarks[rng.Intn(len(punctuationMarks))]
+			if rng.Float64() < 0.5 {
 				word = word + p
 			} else {
 				word = strings.ToUpper(word[:1]) + word[1:]
@@ -83,7 +97,7 @@ func GetRandomQuote(length string) Quote {
 		if len(quotes) == 0 {
 			return Quote{Text: "No quotes available.", Source: "System", Length: "short"}
 		}
-		return quotes[rand.Intn(len(quotes))]
+		return quotes[rng.Intn(len(quotes))]
 	}
-	return filtered[rand.Intn(len(filtered))]
+	return filtered[rng.Intn(len(filtered))]
 }
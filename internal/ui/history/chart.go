@@ -0,0 +1,224 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guptarohit/asciigraph"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+type chartMetric int
+
+const (
+	metricWPM chartMetric = iota
+	metricAccuracy
+	metricConsistency
+	numMetrics
+)
+
+var metricNames = [numMetrics]string{"net wpm", "accuracy", "consistency"}
+
+func (c chartMetric) value(r history.TestResult) float64 {
+	switch c {
+	case metricAccuracy:
+		return r.Accuracy
+	case metricConsistency:
+		return r.Consistency
+	}
+	return r.NetWPM
+}
+
+type chartWindow int
+
+const (
+	windowTests chartWindow = iota
+	windowDays
+	windowWeeks
+	numWindows
+)
+
+// chartWindows describes each window: how many points it shows, and how
+// many points the moving average spans
+var chartWindows = [numWindows]struct {
+	name    string
+	points  int
+	average int
+	unit    string
+}{
+	{"last 50 tests", 50, 10, "test"},
+	{"by day", 60, 7, "day"},
+	{"by week", 52, 4, "week"},
+}
+
+// chartSeries returns the metric per test, day or week for the completed
+// results, oldest first, with its trailing moving average. Both are cut to
+// the window's number of points after averaging, so the first points of the
+// average still cover a full span when older results exist.
+func chartSeries(results []history.TestResult, metric chartMetric, window chartWindow) (values, average []float64) {
+	var sorted []history.TestResult
+	for _, r := range results {
+		if r.Completed() {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	if window == windowTests {
+		for _, r := range sorted {
+			values = append(values, metric.value(r))
+		}
+	} else {
+		var sum float64
+		var n int
+		var current time.Time
+		for _, r := range sorted {
+			start := periodStart(r.Date, window)
+			if !start.Equal(current) && n > 0 {
+				values = append(values, sum/float64(n))
+				sum, n = 0, 0
+			}
+			current = start
+			sum += metric.value(r)
+			n++
+		}
+		if n > 0 {
+			values = append(values, sum/float64(n))
+		}
+	}
+
+	w := chartWindows[window]
+	average = movingAverage(values, w.average)
+	if len(values) > w.points {
+		values = values[len(values)-w.points:]
+		average = average[len(average)-w.points:]
+	}
+	return values, average
+}
+
+// periodStart returns the start of the day or week (from Monday) containing t
+func periodStart(t time.Time, window chartWindow) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	if window == windowWeeks {
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// movingAverage returns the mean of each value and up to span-1 values
+// before it
+func movingAverage(values []float64, span int) []float64 {
+	out := make([]float64, len(values))
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= span {
+			sum -= values[i-span]
+		}
+		out[i] = sum / float64(min(i+1, span))
+	}
+	return out
+}
+
+func (m Model) viewChart() string {
+	t := m.Styles.Theme
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Foreground).Bold(true)
+
+	b.WriteString(titleStyle.Render("Progress"))
+	b.WriteString("\n\n")
+
+	// metric and window selectors
+	for i, name := range metricNames {
+		style := labelStyle
+		if chartMetric(i) == m.chartMetric {
+			style = lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+		}
+		b.WriteString(style.Render(name))
+		b.WriteString("  ")
+	}
+	b.WriteString(labelStyle.Render("|  "))
+	for i, w := range chartWindows {
+		style := labelStyle
+		if chartWindow(i) == m.chartWindow {
+			style = lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+		}
+		b.WriteString(style.Render(w.name))
+		b.WriteString("  ")
+	}
+	b.WriteString("\n")
+	scope := "all results"
+	if m.filters.active() {
+		scope = m.filters.summary()
+	}
+	b.WriteString(labelStyle.Render(fmt.Sprintf("%s, %d tests", scope, len(m.Results))))
+	b.WriteString("\n\n")
+
+	values, average := chartSeries(m.Results, m.chartMetric, m.chartWindow)
+	w := chartWindows[m.chartWindow]
+	if len(values) < 2 {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("Not enough results to chart %s %s.", metricNames[m.chartMetric], w.name)))
+		b.WriteString("\n\n")
+	} else {
+		graphWidth := m.width - 20
+		if graphWidth < 30 {
+			graphWidth = 30
+		}
+		if graphWidth > 100 {
+			graphWidth = 100
+		}
+		graphHeight := m.height - 14
+		if graphHeight < 6 {
+			graphHeight = 6
+		}
+		if graphHeight > 16 {
+			graphHeight = 16
+		}
+		sub := asciigraph.AnsiColor(theme.ANSI256(t.Sub))
+		graph := asciigraph.PlotMany([][]float64{values, average},
+			asciigraph.Width(graphWidth),
+			asciigraph.Height(graphHeight),
+			asciigraph.Precision(1),
+			asciigraph.SeriesColors(
+				asciigraph.AnsiColor(theme.ANSI256(t.Main)),
+				asciigraph.AnsiColor(theme.ANSI256(t.Caret)),
+			),
+			asciigraph.SeriesLegends(metricNames[m.chartMetric], fmt.Sprintf("%d %s average", w.average, w.unit)),
+			asciigraph.AxisColor(sub),
+			asciigraph.LabelColor(sub),
+		)
+		// pad the lines to one width so centering keeps them aligned
+		b.WriteString(lipgloss.NewStyle().Width(lipgloss.Width(graph)).Render(graph))
+		b.WriteString("\n\n")
+
+		unit := " wpm"
+		if m.chartMetric != metricWPM {
+			unit = "%"
+		}
+		change := average[len(average)-1] - average[0]
+		b.WriteString(labelStyle.Render("average now "))
+		b.WriteString(valueStyle.Render(fmt.Sprintf("%.1f%s", average[len(average)-1], unit)))
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %+.1f%s over the last %d %ss", change, unit, len(values), w.unit)))
+		b.WriteString("\n\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	b.WriteString(helpStyle.Render("m metric | w window | f filter | esc back"))
+
+	content := b.String()
+	if m.width > 0 && m.height > 0 {
+		content = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+	}
+	return content
+}
@@ -0,0 +1,255 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guptarohit/asciigraph"
+	"github.com/meszmate/taps/internal/history"
+)
+
+// detail is the state of the detail view of a single result
+type detail struct {
+	result     history.TestResult
+	config     history.Stats // stats of the results with the same config
+	confirming bool          // asking whether to delete the result
+	tagging    bool          // typing a tag
+	tagInput   string
+	err        string
+}
+
+func (m *Model) openDetail() {
+	if m.cursor >= len(m.Results) {
+		return
+	}
+	r := m.Results[m.cursor]
+	config, _ := history.Default().Stats(r.ConfigKey().Filter())
+	m.detail = &detail{result: r, config: config}
+}
+
+func (m *Model) updateDetail(msg tea.KeyMsg) {
+	d := m.detail
+	switch {
+	case d.confirming:
+		switch msg.String() {
+		case "y", "enter":
+			if err := history.Remove(d.result.Date); err != nil {
+				d.confirming = false
+				d.err = fmt.Sprintf("delete failed: %v", err)
+				return
+			}
+			m.removeResult()
+			m.detail = nil
+		default:
+			d.confirming = false
+		}
+
+	case d.tagging:
+		switch msg.Type {
+		case tea.KeyEnter:
+			d.tagging = false
+			if tag := strings.TrimSpace(d.tagInput); tag != "" {
+				m.toggleTag(tag)
+			}
+		case tea.KeyEsc:
+			d.tagging = false
+		case tea.KeyBackspace:
+			if d.tagInput != "" {
+				runes := []rune(d.tagInput)
+				d.tagInput = string(runes[:len(runes)-1])
+			}
+		case tea.KeyRunes:
+			d.tagInput += string(msg.Runes)
+		}
+
+	default:
+		switch msg.String() {
+		case "esc", "q", "enter":
+			m.detail = nil
+		case "d":
+			d.confirming = true
+			d.err = ""
+		case "t":
+			d.tagging = true
+			d.tagInput = ""
+			d.err = ""
+		}
+	}
+}
+
+// toggleTag adds tag to the result in the detail view, or removes it if the
+// result already has it
+func (m *Model) toggleTag(tag string) {
+	d := m.detail
+	tags := slices.Clone(d.result.Tags)
+	if i := slices.Index(tags, tag); i >= 0 {
+		tags = slices.Delete(tags, i, i+1)
+	} else {
+		tags = append(tags, tag)
+	}
+	if err := history.SetTags(d.result.Date, tags); err != nil {
+		d.err = fmt.Sprintf("tagging failed: %v", err)
+		return
+	}
+	d.result.Tags = tags
+	for i := range m.Results {
+		if m.Results[i].Date.Equal(d.result.Date) {
+			m.Results[i].Tags = tags
+		}
+	}
+}
+
+// removeResult reloads the list after a result was deleted, keeping the
+// cursor in place
+func (m *Model) removeResult() {
+	m.total--
+	cursor, scroll := m.cursor, m.scroll
+	m.apply()
+	m.cursor = min(cursor, max(len(m.Results)-1, 0))
+	m.scroll = min(scroll, m.cursor)
+}
+
+func (m Model) viewDetail() string {
+	t := m.Styles.Theme
+	d := m.detail
+	r := d.result
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Foreground).Bold(true)
+
+	b.WriteString(titleStyle.Render("Test Result"))
+	b.WriteString(labelStyle.Render("  " + r.Date.Format("Mon Jan 2 2006 15:04:05")))
+	b.WriteString("\n\n")
+
+	if !r.Completed() {
+		outcome := fmt.Sprintf("%s after %.0fs, not counted in personal bests and averages", r.Outcome, r.TestSeconds())
+		switch {
+		case r.FailReason != "":
+			outcome = fmt.Sprintf("failed: %s, not counted in personal bests and averages", r.FailReason)
+		case r.Outcome == history.OutcomeAFK:
+			outcome = fmt.Sprintf("afk for %.0fs, not counted in personal bests and averages", r.AFKSeconds)
+		}
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Error).Render(outcome))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(lipgloss.NewStyle().Foreground(t.Main).Bold(true).Render(fmt.Sprintf("%.0f", r.NetWPM)))
+	b.WriteString(labelStyle.Render(" wpm  "))
+	type stat struct{ label, value string }
+	stats := []stat{
+		{"raw", fmt.Sprintf("%.0f wpm", r.RawWPM)},
+		{"accuracy", fmt.Sprintf("%.1f%%", r.Accuracy)},
+		{"consistency", fmt.Sprintf("%.1f%%", r.Consistency)},
+	}
+	if r.Completed() && r.AFKSeconds > 0 {
+		stats = append(stats, stat{"afk", fmt.Sprintf("%.0fs paused", r.AFKSeconds)})
+	}
+	if r.PausedSeconds > 0 {
+		stats = append(stats, stat{"paused", fmt.Sprintf("%.0fs", r.PausedSeconds)})
+	}
+	if r.MetricsProfile != "" {
+		stats = append(stats, stat{"metrics", r.MetricsProfile})
+	}
+	for _, s := range stats {
+		b.WriteString(labelStyle.Render(s.label + " "))
+		b.WriteString(valueStyle.Render(s.value))
+		b.WriteString("  ")
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString(labelStyle.Render("characters  "))
+	b.WriteString(lipgloss.NewStyle().Foreground(t.Correct).Render(fmt.Sprintf("%d", r.Correct)))
+	b.WriteString(" / ")
+	b.WriteString(lipgloss.NewStyle().Foreground(t.Error).Render(fmt.Sprintf("%d", r.Incorrect)))
+	b.WriteString(" / ")
+	b.WriteString(lipgloss.NewStyle().Foreground(t.ExtraError).Render(fmt.Sprintf("%d", r.Extra)))
+	b.WriteString(" / ")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("%d", r.Missed)))
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render("            correct / incorrect / extra / missed"))
+	b.WriteString("\n\n")
+
+	if len(r.PerSecondWPM) > 1 {
+		graphWidth := m.width - 20
+		if graphWidth < 30 {
+			graphWidth = 30
+		}
+		if graphWidth > 80 {
+			graphWidth = 80
+		}
+		graph := asciigraph.Plot(r.PerSecondWPM,
+			asciigraph.Width(graphWidth),
+			asciigraph.Height(8),
+			asciigraph.Caption("raw wpm over time"),
+		)
+		b.WriteString(labelStyle.Render(graph))
+	} else {
+		b.WriteString(labelStyle.Render("no wpm graph was recorded for this test"))
+	}
+	b.WriteString("\n\n")
+
+	// Config
+	cfgParts := []string{describe(r), r.Difficulty}
+	switch r.Source {
+	case "":
+	case history.SourceRace:
+		cfgParts = append(cfgParts, "raced")
+	default:
+		cfgParts = append(cfgParts, "imported from "+r.Source)
+	}
+	b.WriteString(labelStyle.Render(strings.Join(cfgParts, " | ")))
+	b.WriteString("\n")
+
+	// Comparison with the other results of the same config
+	cs := d.config
+	b.WriteString(labelStyle.Render("config pb "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%.0f", cs.BestWPM)))
+	if cs.PersonalBest != nil && cs.PersonalBest.Date.Equal(r.Date) {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Correct).Render(" (this test)"))
+	} else {
+		b.WriteString(labelStyle.Render(fmt.Sprintf(" (%+.1f)", r.NetWPM-cs.BestWPM)))
+	}
+	b.WriteString(labelStyle.Render("  config avg "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%.0f", cs.AverageWPM)))
+	b.WriteString(labelStyle.Render(fmt.Sprintf(" (%+.1f)  over %d tests", r.NetWPM-cs.AverageWPM, cs.TotalTests)))
+	b.WriteString("\n\n")
+
+	b.WriteString(labelStyle.Render("tags "))
+	if len(r.Tags) == 0 && !d.tagging {
+		b.WriteString(labelStyle.Render("none"))
+	}
+	for _, tag := range r.Tags {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Main).Render("#" + tag))
+		b.WriteString(" ")
+	}
+	if d.tagging {
+		b.WriteString(valueStyle.Render("#" + d.tagInput + "_"))
+	}
+	b.WriteString("\n\n")
+
+	if d.err != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Error).Render(d.err))
+		b.WriteString("\n\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	switch {
+	case d.confirming:
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Error).Render("delete this result? y/n"))
+	case d.tagging:
+		b.WriteString(helpStyle.Render("enter add or remove tag | esc cancel"))
+	default:
+		b.WriteString(helpStyle.Render("t tag | d delete | esc back"))
+	}
+
+	content := b.String()
+	if m.width > 0 && m.height > 0 {
+		content = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+	}
+	return content
+}
@@ -0,0 +1,298 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/typing"
+)
+
+const anyOption = "any"
+
+// filter fields, in the order they are shown
+const (
+	fieldMode = iota
+	fieldLength
+	fieldLanguage
+	fieldPunctuation
+	fieldNumbers
+	fieldDifficulty
+	fieldOutcome
+	fieldPaused
+	fieldPeriod
+	numFields
+)
+
+var fieldLabels = [numFields]string{"Mode", "Length", "Language", "Punctuation", "Numbers", "Difficulty", "Outcome", "Paused", "Date"}
+
+var periods = []string{"all time", "today", "7 days", "30 days", "this month", "this year"}
+
+// filters is the state of the filter panel: the options of each field and
+// the index of the selected one
+type filters struct {
+	options  [numFields][]string
+	selected [numFields]int
+}
+
+func newFilters(used []history.GroupStats) filters {
+	var f filters
+	f.options[fieldMode] = append([]string{anyOption}, config.Modes...)
+	f.options[fieldLength] = []string{anyOption}
+	f.options[fieldLanguage] = append([]string{anyOption}, languages(used)...)
+	f.options[fieldPunctuation] = []string{anyOption, "on", "off"}
+	f.options[fieldNumbers] = []string{anyOption, "on", "off"}
+	f.options[fieldDifficulty] = append([]string{anyOption}, config.Difficulties...)
+	f.options[fieldOutcome] = append([]string{anyOption}, history.Outcomes...)
+	f.options[fieldPaused] = []string{anyOption, "no", "yes"}
+	f.options[fieldPeriod] = periods
+	return f
+}
+
+// languages returns the known word lists followed by any others results
+// were taken in, given the results grouped by language
+func languages(used []history.GroupStats) []string {
+	langs := slices.Clone(typing.Languages())
+	for _, g := range used {
+		if g.Name != "" && !slices.Contains(langs, g.Name) {
+			langs = append(langs, g.Name)
+		}
+	}
+	return langs
+}
+
+// lengths returns the test lengths used in stored results of mode,
+// ascending
+func lengths(mode string) []string {
+	results, _ := history.Default().Query(history.Filter{Mode: mode})
+	seen := make(map[int]bool)
+	var ls []int
+	for _, r := range results {
+		l := r.Duration
+		if mode == "words" {
+			l = r.WordCount
+		}
+		if l > 0 && !seen[l] {
+			seen[l] = true
+			ls = append(ls, l)
+		}
+	}
+	sort.Ints(ls)
+	out := make([]string, len(ls))
+	for i, l := range ls {
+		out[i] = strconv.Itoa(l)
+	}
+	return out
+}
+
+func (f filters) value(field int) string {
+	return f.options[field][f.selected[field]]
+}
+
+// cycle moves the selection of field by delta, wrapping around. Changing
+// the mode resets the length, whose options depend on it.
+func (f *filters) cycle(field, delta int) {
+	n := len(f.options[field])
+	f.selected[field] = (f.selected[field] + delta + n) % n
+	if field == fieldMode {
+		f.options[fieldLength] = []string{anyOption}
+		if mode := f.value(fieldMode); mode == "time" || mode == "words" {
+			f.options[fieldLength] = append(f.options[fieldLength], lengths(mode)...)
+		}
+		f.selected[fieldLength] = 0
+	}
+}
+
+func (f *filters) clear() {
+	f.selected = [numFields]int{}
+	f.options[fieldLength] = []string{anyOption}
+}
+
+func (f filters) active() bool {
+	return f.selected != [numFields]int{}
+}
+
+// filter returns the history filter for the selected options
+func (f filters) filter(now time.Time) history.Filter {
+	var hf history.Filter
+	if v := f.value(fieldMode); v != anyOption {
+		hf.Mode = v
+	}
+	if v := f.value(fieldLength); v != anyOption {
+		hf.Length, _ = strconv.Atoi(v)
+	}
+	if v := f.value(fieldLanguage); v != anyOption {
+		hf.Language = v
+	}
+	if v := f.value(fieldPunctuation); v != anyOption {
+		on := v == "on"
+		hf.Punctuation = &on
+	}
+	if v := f.value(fieldNumbers); v != anyOption {
+		on := v == "on"
+		hf.Numbers = &on
+	}
+	if v := f.value(fieldDifficulty); v != anyOption {
+		hf.Difficulty = v
+	}
+	if v := f.value(fieldOutcome); v != anyOption {
+		hf.Outcome = v
+	}
+	if v := f.value(fieldPaused); v != anyOption {
+		paused := v == "yes"
+		hf.Paused = &paused
+	}
+
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	switch f.value(fieldPeriod) {
+	case "today":
+		hf.Since = today
+	case "7 days":
+		hf.Since = today.AddDate(0, 0, -6)
+	case "30 days":
+		hf.Since = today.AddDate(0, 0, -29)
+	case "this month":
+		hf.Since = time.Date(y, mo, 1, 0, 0, 0, 0, now.Location())
+	case "this year":
+		hf.Since = time.Date(y, 1, 1, 0, 0, 0, 0, now.Location())
+	}
+	return hf
+}
+
+// summary describes the active filters in one line
+func (f filters) summary() string {
+	var parts []string
+	mode := f.value(fieldMode)
+	if mode != anyOption {
+		parts = append(parts, mode)
+	}
+	if l := f.value(fieldLength); l != anyOption {
+		if mode == "time" {
+			l += "s"
+		} else {
+			l += " words"
+		}
+		parts = append(parts, l)
+	}
+	if v := f.value(fieldLanguage); v != anyOption {
+		parts = append(parts, v)
+	}
+	for _, field := range []int{fieldPunctuation, fieldNumbers} {
+		if v := f.value(field); v != anyOption {
+			parts = append(parts, fmt.Sprintf("%s %s", strings.ToLower(fieldLabels[field]), v))
+		}
+	}
+	for _, field := range []int{fieldDifficulty, fieldOutcome} {
+		if v := f.value(field); v != anyOption {
+			parts = append(parts, v)
+		}
+	}
+	switch f.value(fieldPaused) {
+	case "no":
+		parts = append(parts, "not paused")
+	case "yes":
+		parts = append(parts, "paused")
+	}
+	if v := f.value(fieldPeriod); v != periods[0] {
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type sortKey int
+
+const (
+	sortDate sortKey = iota
+	sortWPM
+	sortAccuracy
+	sortConsistency
+	numSortKeys
+)
+
+var sortNames = [numSortKeys]string{"date", "wpm", "accuracy", "consistency"}
+
+// sortResults orders results by key, highest or newest first unless
+// ascending. Ties keep the newest result first.
+func sortResults(results []history.TestResult, key sortKey, ascending bool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Date.After(results[j].Date)
+	})
+	if key == sortDate {
+		if ascending {
+			slices.Reverse(results)
+		}
+		return
+	}
+
+	value := func(r history.TestResult) float64 {
+		switch key {
+		case sortAccuracy:
+			return r.Accuracy
+		case sortConsistency:
+			return r.Consistency
+		}
+		return r.NetWPM
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if ascending {
+			return value(results[i]) < value(results[j])
+		}
+		return value(results[i]) > value(results[j])
+	})
+}
+
+// matchesSearch reports whether every word of query appears in the result's
+// date, config, outcome, metrics profile or tags
+func matchesSearch(r history.TestResult, query string) bool {
+	text := strings.ToLower(strings.Join([]string{
+		r.Date.Format("2006-01-02 01/02 15:04 Jan January Mon Monday"),
+		describe(r),
+		r.Difficulty,
+		r.Source,
+		r.Outcome,
+		r.MetricsProfile,
+		paused(r),
+		strings.Join(r.Tags, " "),
+	}, " "))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// paused marks results of tests paused with the pause key
+func paused(r history.TestResult) string {
+	if r.PausedSeconds > 0 {
+		return "paused"
+	}
+	return ""
+}
+
+// describe lists the config of a result as shown in the list
+func describe(r history.TestResult) string {
+	parts := []string{r.Mode}
+	switch r.Mode {
+	case "time":
+		parts = append(parts, fmt.Sprintf("%ds", r.Duration))
+	case "words":
+		parts = append(parts, strconv.Itoa(r.WordCount))
+	case "quote":
+		parts = append(parts, r.QuoteLength)
+	}
+	parts = append(parts, r.Language)
+	if r.Punctuation {
+		parts = append(parts, "punct")
+	}
+	if r.Numbers {
+		parts = append(parts, "num")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,152 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+// heatmapLevels is the number of shades used for days with practice
+const heatmapLevels = 4
+
+// heatmapWeeks returns how many weeks of activity fit the screen
+func (m Model) heatmapWeeks() int {
+	weeks := (m.width - 12) / 2
+	return max(min(weeks, 53), 10)
+}
+
+func (m Model) viewHeatmap() string {
+	t := m.Styles.Theme
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Foreground).Bold(true)
+
+	b.WriteString(titleStyle.Render("Activity"))
+	b.WriteString("\n\n")
+
+	now := time.Now()
+	days := history.Activity(m.Results)
+	byDay := make(map[time.Time]history.Day, len(days))
+	for _, d := range days {
+		byDay[d.Date] = d
+	}
+
+	// columns are weeks from Monday, ending with the current week
+	weeks := m.heatmapWeeks()
+	today := periodStart(now, windowDays)
+	first := periodStart(now, windowWeeks).AddDate(0, 0, -7*(weeks-1))
+
+	var most time.Duration
+	var shown history.Day
+	for _, d := range days {
+		if d.Date.Before(first) {
+			continue
+		}
+		most = max(most, d.Practice)
+		shown.Tests += d.Tests
+		shown.Practice += d.Practice
+	}
+
+	shades := make([]lipgloss.Style, heatmapLevels+1)
+	shades[0] = lipgloss.NewStyle().Foreground(theme.Blend(t.Background, t.Sub, 0.4))
+	for i := 1; i <= heatmapLevels; i++ {
+		c := theme.Blend(t.Background, t.Main, 0.25+0.75*float64(i)/heatmapLevels)
+		shades[i] = lipgloss.NewStyle().Foreground(c)
+	}
+	level := func(d history.Day) int {
+		if d.Tests == 0 && d.Practice == 0 {
+			return 0
+		}
+		if most <= 0 {
+			return heatmapLevels
+		}
+		return max(int(math.Ceil(float64(heatmapLevels)*float64(d.Practice)/float64(most))), 1)
+	}
+
+	// month labels above the first week starting in each month, skipped
+	// where the previous label has no room to end
+	var months strings.Builder
+	used := 0
+	for w := 0; w < weeks; w++ {
+		start := first.AddDate(0, 0, 7*w)
+		if w > 0 && start.Month() == start.AddDate(0, 0, -7).Month() {
+			continue
+		}
+		if w*2 < used {
+			continue
+		}
+		months.WriteString(strings.Repeat(" ", w*2-used))
+		months.WriteString(start.Format("Jan"))
+		used = w*2 + 3
+	}
+	rows := []string{labelStyle.Render("    " + months.String())}
+
+	for weekday := 0; weekday < 7; weekday++ {
+		var row strings.Builder
+		name := ""
+		if weekday%2 == 0 {
+			name = first.AddDate(0, 0, weekday).Format("Mon")
+		}
+		row.WriteString(labelStyle.Render(fmt.Sprintf("%-4s", name)))
+		for w := 0; w < weeks; w++ {
+			date := first.AddDate(0, 0, 7*w+weekday)
+			if date.After(today) {
+				break
+			}
+			row.WriteString(shades[level(byDay[date])].Render("■"))
+			row.WriteString(" ")
+		}
+		rows = append(rows, row.String())
+	}
+
+	legend := labelStyle.Render("    less ")
+	for _, s := range shades {
+		legend += s.Render("■") + " "
+	}
+	rows = append(rows, "", legend+labelStyle.Render("more practice time"))
+
+	b.WriteString(lipgloss.JoinVertical(lipgloss.Left, rows...))
+	b.WriteString("\n\n")
+
+	current, longest := history.Streak(days, now)
+	scope := "all results"
+	if m.filters.active() {
+		scope = m.filters.summary()
+	}
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%d", shown.Tests)))
+	b.WriteString(labelStyle.Render(" tests  "))
+	b.WriteString(valueStyle.Render(formatPractice(shown.Practice)))
+	b.WriteString(labelStyle.Render(fmt.Sprintf(" practiced in the last %d weeks, %s", weeks, scope)))
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render("current streak "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%d", current)))
+	b.WriteString(labelStyle.Render("  longest streak "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%d", longest)))
+	b.WriteString(labelStyle.Render(" days"))
+	b.WriteString("\n\n")
+
+	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	b.WriteString(helpStyle.Render("f filter | esc back"))
+
+	content := b.String()
+	if m.width > 0 && m.height > 0 {
+		content = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+	}
+	return content
+}
+
+// formatPractice formats a practice time as hours and minutes
+func formatPractice(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -3,6 +3,7 @@ package history
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -13,24 +14,68 @@ import (
 type BackToMenuMsg struct{}
 
 type Model struct {
-	Styles  *styles.Styles
-	Results []history.TestResult
-	Stats   history.Stats
-	cursor  int
-	scroll  int
-	width   int
-	height  int
+	Styles *styles.Styles
+	// Results are the results shown, after filtering and sorting, and Stats
+	// are calculated from them
+	Results     []history.TestResult
+	Stats       history.Stats
+	total       int // results in the history, before filtering
+	filters     filters
+	filtering   bool // filter panel open
+	field       int  // selected filter field
+	sortKey     sortKey
+	ascending   bool
+	searching   bool // editing the search query
+	search      string
+	cursor      int
+	scroll      int
+	width       int
+	height      int
+	detail      *detail // result opened in the detail view, if any
+	charting    bool    // showing the progress chart
+	chartMetric chartMetric
+	chartWindow chartWindow
+	activity    bool // showing the activity heatmap
+	showStats   bool // showing the full statistics
+	exporting   bool // choosing an export format
+	exportIdx   int
+	status      string
 }
 
 func New(s *styles.Styles) Model {
-	results, _ := history.Load()
-	stats := history.CalculateStats(results)
+	all, _ := history.Default().Stats(history.Filter{})
 
-	return Model{
+	m := Model{
 		Styles:  s,
-		Results: results,
-		Stats:   stats,
+		total:   all.TotalTests,
+		filters: newFilters(all.ByLanguage),
 	}
+	m.apply()
+	return m
+}
+
+// apply recomputes the shown results and their stats from the filters,
+// search query and sort order. The filters are left to the store; the
+// search is matched here.
+func (m *Model) apply() {
+	store := history.Default()
+	f := m.filters.filter(time.Now())
+	results, _ := store.Query(f)
+	var shown []history.TestResult
+	for _, r := range results {
+		if matchesSearch(r, m.search) {
+			shown = append(shown, r)
+		}
+	}
+	sortResults(shown, m.sortKey, m.ascending)
+	m.Results = shown
+	if m.search == "" {
+		m.Stats, _ = store.Stats(f)
+	} else {
+		m.Stats = history.CalculateStats(shown)
+	}
+	m.cursor = 0
+	m.scroll = 0
 }
 
 func (m Model) Init() tea.Cmd {
@@ -44,9 +89,66 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case tea.KeyMsg:
+		if m.detail != nil {
+			m.updateDetail(msg)
+			return m, nil
+		}
+		if m.exporting {
+			m.updateExport(msg)
+			return m, nil
+		}
+		if m.filtering {
+			m.updateFilters(msg)
+			return m, nil
+		}
+		if m.searching {
+			m.updateSearch(msg)
+			return m, nil
+		}
+		if m.charting {
+			m.updateChart(msg)
+			return m, nil
+		}
+		if m.activity || m.showStats {
+			switch msg.String() {
+			case "esc", "q", "a", "i":
+				m.activity = false
+				m.showStats = false
+			case "f":
+				m.filtering = true
+			}
+			return m, nil
+		}
 		switch msg.String() {
 		case "esc", "q":
 			return m, func() tea.Msg { return BackToMenuMsg{} }
+		case "x":
+			if len(m.Results) > 0 {
+				m.exporting = true
+				m.status = ""
+			}
+		case "enter":
+			m.openDetail()
+		case "g":
+			m.charting = true
+		case "a":
+			m.activity = true
+		case "i":
+			m.showStats = true
+		case "f":
+			m.filtering = true
+		case "/":
+			m.searching = true
+		case "s":
+			m.sortKey = (m.sortKey + 1) % numSortKeys
+			m.apply()
+		case "r":
+			m.ascending = !m.ascending
+			m.apply()
+		case "c":
+			m.filters.clear()
+			m.search = ""
+			m.apply()
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
@@ -57,10 +159,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case "down", "j":
 			if m.cursor < len(m.Results)-1 {
 				m.cursor++
-				visibleLines := m.height - 12
-				if visibleLines < 5 {
-					visibleLines = 10
-				}
+				visibleLines := m.visibleLines()
 				if m.cursor >= m.scroll+visibleLines {
 					m.scroll = m.cursor - visibleLines + 1
 				}
@@ -72,7 +171,93 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m *Model) updateFilters(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc", "q", "enter", "f":
+		m.filtering = false
+	case "up", "k":
+		m.field = (m.field + numFields - 1) % numFields
+	case "down", "j":
+		m.field = (m.field + 1) % numFields
+	case "left", "h":
+		m.filters.cycle(m.field, -1)
+		m.apply()
+	case "right", "l":
+		m.filters.cycle(m.field, 1)
+		m.apply()
+	case "c":
+		m.filters.clear()
+		m.apply()
+	}
+}
+
+func (m *Model) updateChart(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc", "q", "g":
+		m.charting = false
+	case "m", "tab":
+		m.chartMetric = (m.chartMetric + 1) % numMetrics
+	case "w", "right", "l":
+		m.chartWindow = (m.chartWindow + 1) % numWindows
+	case "left", "h":
+		m.chartWindow = (m.chartWindow + numWindows - 1) % numWindows
+	case "f":
+		m.filtering = true
+	}
+}
+
+func (m *Model) updateSearch(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+	case tea.KeyEsc:
+		m.searching = false
+		m.search = ""
+		m.apply()
+	case tea.KeyBackspace:
+		if m.search != "" {
+			runes := []rune(m.search)
+			m.search = string(runes[:len(runes)-1])
+			m.apply()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.search += string(msg.Runes)
+		m.apply()
+	}
+}
+
+func (m *Model) updateExport(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc", "q":
+		m.exporting = false
+	case "left", "h":
+		m.exportIdx = (m.exportIdx + len(history.Formats) - 1) % len(history.Formats)
+	case "right", "l":
+		m.exportIdx = (m.exportIdx + 1) % len(history.Formats)
+	case "enter":
+		m.exporting = false
+		p, err := history.ExportFile(m.Results, history.Formats[m.exportIdx])
+		if err != nil {
+			m.status = fmt.Sprintf("export failed: %v", err)
+			return
+		}
+		m.status = fmt.Sprintf("exported %d results to %s", len(m.Results), p)
+	}
+}
+
 func (m Model) View() string {
+	if m.detail != nil {
+		return m.viewDetail()
+	}
+	if m.charting && !m.filtering {
+		return m.viewChart()
+	}
+	if m.activity && !m.filtering {
+		return m.viewHeatmap()
+	}
+	if m.showStats && !m.filtering {
+		return m.viewStats()
+	}
 	t := m.Styles.Theme
 	var b strings.Builder
 
@@ -95,36 +280,32 @@ func (m Model) View() string {
 	b.WriteString("  ")
 	b.WriteString(statLabel.Render("last 10 avg "))
 	b.WriteString(statValue.Render(fmt.Sprintf("%.0f", m.Stats.Last10Avg)))
+	b.WriteString("\n")
+	b.WriteString(statLabel.Render(m.describeView()))
 	b.WriteString("\n\n")
 
-	if len(m.Results) == 0 {
+	if m.filtering {
+		m.viewFilters(&b)
+	} else if m.total == 0 {
 		dimStyle := lipgloss.NewStyle().Foreground(t.Sub)
 		b.WriteString(dimStyle.Render("No test history yet. Complete a test to see results here."))
+	} else if len(m.Results) == 0 {
+		dimStyle := lipgloss.NewStyle().Foreground(t.Sub)
+		b.WriteString(dimStyle.Render("No results match the filters. Press c to clear them."))
 	} else {
 		// Header
 		headerStyle := lipgloss.NewStyle().Foreground(t.Sub).Bold(true)
-		b.WriteString(headerStyle.Render(fmt.Sprintf("  %-12s %-8s %-8s %-10s %-10s %s", "Date", "Mode", "WPM", "Accuracy", "Consist.", "Config")))
+		b.WriteString(headerStyle.Render(fmt.Sprintf("  %-12s %-10s %-8s %-10s %-10s %s", "Date", "Mode", "WPM", "Accuracy", "Consist.", "Config")))
 		b.WriteString("\n")
 
-		visibleLines := m.height - 12
-		if visibleLines < 5 {
-			visibleLines = 15
-		}
-
+		visibleLines := m.visibleLines()
 		endIdx := m.scroll + visibleLines
 		if endIdx > len(m.Results) {
 			endIdx = len(m.Results)
 		}
 
-		// Show results in reverse chronological order
-		reversed := make([]int, len(m.Results))
-		for i := range m.Results {
-			reversed[i] = len(m.Results) - 1 - i
-		}
-
-		for i := m.scroll; i < endIdx && i < len(reversed); i++ {
-			ri := reversed[i]
-			r := m.Results[ri]
+		for i := m.scroll; i < endIdx; i++ {
+			r := m.Results[i]
 			cursor := "  "
 			lineStyle := lipgloss.NewStyle().Foreground(t.Sub)
 			if i == m.cursor {
@@ -140,17 +321,61 @@ func (m Model) View() string {
 			if r.Numbers {
 				cfgParts = append(cfgParts, "num")
 			}
+			if !r.Completed() {
+				cfgParts = append(cfgParts, r.Outcome)
+			}
+			if p := paused(r); p != "" {
+				cfgParts = append(cfgParts, p)
+			}
+			for _, tag := range r.Tags {
+				cfgParts = append(cfgParts, "#"+tag)
+			}
+
+			mode := r.Mode
+			switch r.Mode {
+			case "time":
+				mode = fmt.Sprintf("time %d", r.Duration)
+			case "words":
+				mode = fmt.Sprintf("words %d", r.WordCount)
+			}
 
-			line := fmt.Sprintf("%-12s %-8s %-8.0f %-10.1f%% %-10.1f%% %s",
-				date, r.Mode, r.NetWPM, r.Accuracy, r.Consistency, strings.Join(cfgParts, ","))
+			line := fmt.Sprintf("%-12s %-10s %-8.0f %-10.1f%% %-10.1f%% %s",
+				date, mode, r.NetWPM, r.Accuracy, r.Consistency, strings.Join(cfgParts, ","))
 			b.WriteString(lineStyle.Render(cursor + line))
 			b.WriteString("\n")
 		}
 	}
 
 	b.WriteString("\n")
+	if m.exporting {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Sub).Render("export as  "))
+		for i, f := range history.Formats {
+			style := lipgloss.NewStyle().Foreground(t.Sub)
+			if i == m.exportIdx {
+				style = lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+			}
+			b.WriteString(style.Render(string(f)))
+			if i < len(history.Formats)-1 {
+				b.WriteString("  ")
+			}
+		}
+		b.WriteString("\n\n")
+	} else if m.status != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Foreground).Render(m.status))
+		b.WriteString("\n\n")
+	}
+
 	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
-	b.WriteString(helpStyle.Render("up/down scroll | esc back"))
+	switch {
+	case m.exporting:
+		b.WriteString(helpStyle.Render("left/right format | enter export | esc cancel"))
+	case m.filtering:
+		b.WriteString(helpStyle.Render("up/down field | left/right change | c clear | esc done"))
+	case m.searching:
+		b.WriteString(helpStyle.Render("type to search date, config or tags | enter done | esc clear"))
+	default:
+		b.WriteString(helpStyle.Render("up/down scroll | enter details | i stats | g chart | a activity | f filter | / search | s sort | r reverse | x export | esc back"))
+	}
 
 	content := b.String()
 	if m.width > 0 && m.height > 0 {
@@ -159,3 +384,68 @@ func (m Model) View() string {
 
 	return content
 }
+
+func (m Model) visibleLines() int {
+	visibleLines := m.height - 13
+	if visibleLines < 5 {
+		visibleLines = 15
+	}
+	return visibleLines
+}
+
+// describeView summarizes which results are shown and in what order
+func (m Model) describeView() string {
+	parts := []string{fmt.Sprintf("showing %d of %d", len(m.Results), m.total)}
+	if m.filters.active() {
+		parts = append(parts, m.filters.summary())
+	}
+	if m.searching {
+		parts = append(parts, fmt.Sprintf("search: %s_", m.search))
+	} else if m.search != "" {
+		parts = append(parts, fmt.Sprintf("search: %q", m.search))
+	}
+	order := "highest first"
+	if m.sortKey == sortDate {
+		order = "newest first"
+		if m.ascending {
+			order = "oldest first"
+		}
+	} else if m.ascending {
+		order = "lowest first"
+	}
+	parts = append(parts, fmt.Sprintf("by %s, %s", sortNames[m.sortKey], order))
+	return strings.Join(parts, " | ")
+}
+
+func (m Model) viewFilters(b *strings.Builder) {
+	t := m.Styles.Theme
+	rows := make([]string, numFields)
+	for i := range rows {
+		labelStyle := lipgloss.NewStyle().Foreground(t.Sub).Width(14)
+		cursor := "  "
+		if i == m.field {
+			labelStyle = lipgloss.NewStyle().Foreground(t.Main).Width(14).Bold(true)
+			cursor = "> "
+		}
+		var row strings.Builder
+		row.WriteString(cursor)
+		row.WriteString(labelStyle.Render(fieldLabels[i]))
+		row.WriteString("  ")
+
+		opts := m.filters.options[i]
+		for j, opt := range opts {
+			optStyle := lipgloss.NewStyle().Foreground(t.Sub)
+			if j == m.filters.selected[i] {
+				optStyle = lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+			}
+			row.WriteString(optStyle.Render(opt))
+			if j < len(opts)-1 {
+				row.WriteString("  ")
+			}
+		}
+		rows[i] = row.String()
+	}
+	// pad the rows to one width so centering keeps them aligned
+	b.WriteString(lipgloss.JoinVertical(lipgloss.Left, rows...))
+	b.WriteString("\n")
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/meszmate/taps/internal/history"
+)
+
+func (m Model) viewStats() string {
+	t := m.Styles.Theme
+	s := m.Stats
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Foreground).Bold(true)
+
+	b.WriteString(titleStyle.Render("Statistics"))
+	b.WriteString("\n")
+	scope := "all results"
+	if m.filters.active() {
+		scope = m.filters.summary()
+	}
+	if m.search != "" {
+		scope += fmt.Sprintf(", matching %q", m.search)
+	}
+	b.WriteString(labelStyle.Render(fmt.Sprintf("%s, %d tests", scope, len(m.Results))))
+	b.WriteString("\n\n")
+
+	if s.TotalTests == 0 {
+		b.WriteString(labelStyle.Render("No results to summarize."))
+		b.WriteString("\n\n")
+	} else {
+		// pairs renders label value pairs on one line
+		pairs := func(kv ...string) string {
+			var line strings.Builder
+			for i := 0; i+1 < len(kv); i += 2 {
+				line.WriteString(labelStyle.Render(kv[i] + " "))
+				line.WriteString(valueStyle.Render(kv[i+1]))
+				line.WriteString("  ")
+			}
+			return line.String()
+		}
+		row := func(label, content string) string {
+			return labelStyle.Render(fmt.Sprintf("%-13s", label)) + content
+		}
+		wpm := func(v float64) string { return fmt.Sprintf("%.0f", v) }
+		pct := func(v float64) string { return fmt.Sprintf("%.1f%%", v) }
+
+		trend := "needs a week of results"
+		if s.ImprovementPerWeek != 0 {
+			trend = fmt.Sprintf("%+.2f wpm per week", s.ImprovementPerWeek)
+		}
+		rows := []string{
+			row("speed", pairs("avg", wpm(s.AverageWPM), "median", wpm(s.MedianWPM),
+				"std dev", fmt.Sprintf("%.1f", s.StdDevWPM), "best", wpm(s.BestWPM), "last 10", wpm(s.Last10Avg))),
+			row("percentiles", pairs("p10", wpm(s.Percentiles.P10), "p25", wpm(s.Percentiles.P25),
+				"p75", wpm(s.Percentiles.P75), "p90", wpm(s.Percentiles.P90))),
+			row("quality", pairs("accuracy", pct(s.AverageAccuracy), "consistency", pct(s.AverageConsistency))),
+			row("tests", pairs("completed", fmt.Sprint(s.Completed),
+				"failed", fmt.Sprintf("%d (%.1f%%)", s.Failed, s.Rate(s.Failed)),
+				"aborted", fmt.Sprintf("%d (%.1f%%)", s.Aborted, s.Rate(s.Aborted)),
+				"afk", fmt.Sprintf("%d (%.1f%%)", s.AFK, s.Rate(s.AFK)))),
+			row("typed", pairs("time", formatPractice(time.Duration(s.TotalSeconds*float64(time.Second))),
+				"words", fmt.Sprint(s.TotalWords))),
+			row("trend", valueStyle.Render(trend)),
+		}
+		rows = append(rows, "", titleStyle.Render("by mode"))
+		rows = append(rows, m.groupRows(s.ByMode)...)
+		rows = append(rows, "", titleStyle.Render("by language"))
+		rows = append(rows, m.groupRows(s.ByLanguage)...)
+
+		b.WriteString(lipgloss.JoinVertical(lipgloss.Left, rows...))
+		b.WriteString("\n\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	b.WriteString(helpStyle.Render("f filter | esc back"))
+
+	content := b.String()
+	if m.width > 0 && m.height > 0 {
+		content = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+	}
+	return content
+}
+
+// groupRows renders a line per mode or language breakdown
+func (m Model) groupRows(groups []history.GroupStats) []string {
+	t := m.Styles.Theme
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Foreground)
+
+	var rows []string
+	for _, g := range groups {
+		name := g.Name
+		if name == "" {
+			name = "unknown"
+		}
+		rows = append(rows, labelStyle.Render(fmt.Sprintf("%-13s", name))+
+			valueStyle.Render(fmt.Sprintf("%6d", g.Tests))+labelStyle.Render(" tests  avg ")+
+			valueStyle.Render(fmt.Sprintf("%4.0f", g.AverageWPM))+labelStyle.Render("  best ")+
+			valueStyle.Render(fmt.Sprintf("%4.0f", g.BestWPM))+labelStyle.Render("  acc ")+
+			valueStyle.Render(fmt.Sprintf("%5.1f%%", g.AverageAccuracy)))
+	}
+	return rows
+}
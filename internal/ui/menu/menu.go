@@ -3,10 +3,12 @@ package menu
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/history"
 	"github.com/meszmate/taps/internal/ui/styles"
 	"github.com/meszmate/taps/internal/ui/theme"
 )
@@ -36,6 +38,9 @@ type Model struct {
 	durations  []int
 	wcIdx      int
 	wordCounts []int
+	bests      map[history.ConfigKey]history.TestResult
+	days       []history.Day
+	targetBest float64 // best net wpm in the config of the wpm goal
 	width      int
 	height     int
 }
@@ -67,9 +72,25 @@ func New(cfg *config.Config, s *styles.Styles) Model {
 		}
 	}
 
+	store := history.Default()
+	pbs, _ := store.PersonalBests(history.Filter{})
+	bests := make(map[history.ConfigKey]history.TestResult, len(pbs))
+	targetBest := 0.0
+	for _, r := range pbs {
+		key := r.ConfigKey()
+		bests[key] = r
+		if key.Mode == cfg.Goals.TargetMode && key.Length == cfg.Goals.TargetLength {
+			targetBest = max(targetBest, r.NetWPM)
+		}
+	}
+	days, _ := store.Activity(history.Filter{})
+
 	return Model{
-		Config: cfg,
-		Styles: s,
+		Config:     cfg,
+		Styles:     s,
+		bests:      bests,
+		days:       days,
+		targetBest: targetBest,
 		items: []menuItem{
 			{label: "Start Test", action: actionStart},
 			{label: "Settings", action: actionSettings},
@@ -207,6 +228,16 @@ func (m Model) View() string {
 	b.WriteString(titleStyle.Render(theme.RainbowText("Taps", string(t.Main), string(t.Caret))))
 	b.WriteString("\n\n")
 
+	// Problems found while loading the config
+	if warnings := m.Config.Warnings(); len(warnings) > 0 {
+		warnStyle := lipgloss.NewStyle().Foreground(t.Error)
+		for _, w := range warnings {
+			b.WriteString(warnStyle.Render("config: " + w))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
 	// Mode selector
 	modeLabel := lipgloss.NewStyle().Foreground(t.Sub).Render("mode  ")
 	b.WriteString(modeLabel)
@@ -285,6 +316,19 @@ func (m Model) View() string {
 	b.WriteString(numStyle.Render(fmt.Sprintf("# numbers %s", boolIcon(m.Config.Numbers))))
 	b.WriteString("\n\n")
 
+	// Personal best for the selected config
+	pbLabel := lipgloss.NewStyle().Foreground(t.Sub)
+	if pb, ok := m.bests[m.configKey()]; ok {
+		b.WriteString(pbLabel.Render("pb "))
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Main).Bold(true).Render(fmt.Sprintf("%.0f wpm", pb.NetWPM)))
+		b.WriteString(pbLabel.Render(fmt.Sprintf("  %.1f%% acc  %s", pb.Accuracy, pb.Date.Format("Jan 2 2006"))))
+	} else {
+		b.WriteString(pbLabel.Render("no personal best for this config yet"))
+	}
+	b.WriteString("\n")
+	b.WriteString(m.viewGoals())
+	b.WriteString("\n\n")
+
 	// Menu items
 	for i, item := range m.items {
 		style := lipgloss.NewStyle().Foreground(t.Sub)
@@ -311,6 +355,80 @@ func (m Model) View() string {
 	return content
 }
 
+// viewGoals shows today's progress towards the practice goals, the wpm goal
+// and the current streak
+func (m Model) viewGoals() string {
+	t := m.Styles.Theme
+	g := m.Config.Goals
+	label := lipgloss.NewStyle().Foreground(t.Sub)
+	now := time.Now()
+	today := history.Today(m.days, now)
+
+	var parts []string
+	if g.MinutesPerDay > 0 {
+		parts = append(parts, m.goalProgress(today.Practice.Minutes(), float64(g.MinutesPerDay), "min today"))
+	}
+	if g.TestsPerDay > 0 {
+		parts = append(parts, m.goalProgress(float64(today.Tests), float64(g.TestsPerDay), "tests today"))
+	}
+	if g.TargetWPM > 0 {
+		parts = append(parts, m.goalProgress(m.targetBest, float64(g.TargetWPM), fmt.Sprintf("wpm in %s %d", g.TargetMode, g.TargetLength)))
+	}
+
+	current, longest := history.Streak(m.days, now)
+	streak := label.Render("no streak yet, practice today to start one")
+	if current > 0 {
+		style := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+		if today.Tests == 0 {
+			// not practiced yet today, the streak ends at midnight
+			style = lipgloss.NewStyle().Foreground(t.Foreground)
+		}
+		streak = label.Render("streak ") + style.Render(pluralize(current, "day")) +
+			label.Render(fmt.Sprintf("  best %s", pluralize(longest, "day")))
+	}
+	if len(parts) == 0 {
+		return streak
+	}
+	return strings.Join(parts, label.Render("   ")) + "\n" + streak
+}
+
+// goalProgress renders a progress bar towards goal
+func (m Model) goalProgress(done, goal float64, unit string) string {
+	t := m.Styles.Theme
+	const width = 10
+	filled := int(min(done/goal, 1) * width)
+	barColor := t.Main
+	if done >= goal {
+		barColor = t.Correct
+	}
+	return lipgloss.NewStyle().Foreground(barColor).Render(strings.Repeat("━", filled)) +
+		lipgloss.NewStyle().Foreground(t.Sub).Render(strings.Repeat("─", width-filled)) +
+		lipgloss.NewStyle().Foreground(t.Foreground).Render(fmt.Sprintf(" %d/%.0f ", int(done), goal)) +
+		lipgloss.NewStyle().Foreground(t.Sub).Render(unit)
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// configKey returns the personal best key of the test the menu would start
+func (m Model) configKey() history.ConfigKey {
+	return history.TestResult{
+		Mode:           m.modes[m.modeIdx],
+		Duration:       m.durations[m.durIdx],
+		WordCount:      m.wordCounts[m.wcIdx],
+		QuoteLength:    m.Config.QuoteLength,
+		Language:       m.Config.Language,
+		Punctuation:    m.Config.Punctuation,
+		Numbers:        m.Config.Numbers,
+		Difficulty:     m.Config.Difficulty,
+		MetricsProfile: m.Config.MetricsProfile,
+	}.ConfigKey()
+}
+
 func boolIcon(v bool) string {
 	if v {
 		return "on"
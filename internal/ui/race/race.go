@@ -0,0 +1,345 @@
+// Package race is the screen of a race against other taps instances: the
+// lobby, the countdown, the race itself and the final standings.
+package race
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/race"
+	"github.com/meszmate/taps/internal/ui/styles"
+	"github.com/meszmate/taps/internal/ui/test"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+// countdown is the seconds counted down between the start and the race
+const countdown = 3
+
+// reportInterval is how often progress is sent to the host while typing
+const reportInterval = 250 * time.Millisecond
+
+type phase int
+
+const (
+	phaseLobby phase = iota
+	phaseCountdown
+	phaseRacing
+	phaseDone
+)
+
+// LeaveMsg is sent when the player leaves the race screen
+type LeaveMsg struct{}
+
+type serverMsg race.Message
+type disconnectedMsg struct{ err error }
+type countdownMsg struct{}
+type reportMsg struct{}
+
+type Model struct {
+	Config *config.Config
+	Styles *styles.Styles
+	Client *race.Client
+	// Server is the race when hosted by this player, nil when joined
+	Server *race.Server
+	Width  int
+	Height int
+
+	phase   phase
+	players []race.Player
+	race    *race.Race
+	count   int
+	test    test.Model
+	begin   time.Time // when the countdown ended
+	err     error
+}
+
+func New(cfg *config.Config, s *styles.Styles, client *race.Client, server *race.Server) Model {
+	return Model{
+		Config: cfg,
+		Styles: s,
+		Client: client,
+		Server: server,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return m.next()
+}
+
+// next waits for the next message from the host
+func (m Model) next() tea.Cmd {
+	c := m.Client
+	return func() tea.Msg {
+		msg, err := c.Next()
+		if err != nil {
+			return disconnectedMsg{err}
+		}
+		return serverMsg(msg)
+	}
+}
+
+func countdownCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return countdownMsg{} })
+}
+
+func reportCmd() tea.Cmd {
+	return tea.Tick(reportInterval, func(time.Time) tea.Msg { return reportMsg{} })
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.Width = msg.Width
+		m.Height = msg.Height
+
+	case serverMsg:
+		return m.handle(race.Message(msg))
+
+	case disconnectedMsg:
+		if m.phase != phaseDone {
+			m.err = msg.err
+		}
+		return m, nil
+
+	case countdownMsg:
+		m.count--
+		if m.count > 0 {
+			return m, countdownCmd()
+		}
+		m.phase = phaseRacing
+		m.begin = time.Now()
+		return m, reportCmd()
+
+	case reportMsg:
+		if m.phase != phaseRacing || m.test.Engine.Finished || m.test.Engine.Failed {
+			return m, nil
+		}
+		m.report()
+		return m, reportCmd()
+
+	case test.TestFinishedMsg:
+		m.report()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m, func() tea.Msg { return LeaveMsg{} }
+		}
+		switch m.phase {
+		case phaseLobby:
+			if msg.String() == "enter" && m.Server != nil {
+				if err := m.Server.Start(); err != nil {
+					m.err = err
+				}
+			}
+			return m, nil
+		case phaseRacing:
+			if m.test.Engine.Finished || m.test.Engine.Failed {
+				// the player is done and waits for the others
+				return m, nil
+			}
+			switch msg.String() {
+			case "tab", "ctrl+p":
+				// no restarting or pausing in the middle of a race
+				return m, nil
+			}
+		case phaseDone:
+			if msg.String() == "enter" {
+				return m, func() tea.Msg { return LeaveMsg{} }
+			}
+			return m, nil
+		default:
+			return m, nil
+		}
+	}
+
+	if m.phase == phaseRacing {
+		var cmd tea.Cmd
+		m.test, cmd = m.test.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// handle applies a message from the host
+func (m Model) handle(msg race.Message) (Model, tea.Cmd) {
+	cmds := []tea.Cmd{m.next()}
+	switch msg.Type {
+	case race.TypeLobby:
+		m.players = msg.Players
+	case race.TypeStart:
+		r := msg.Race
+		m.race = r
+		tcfg := test.TestConfig{
+			Mode:        "words",
+			WordCount:   r.WordCount,
+			Language:    r.Language,
+			Punctuation: r.Punctuation,
+			Numbers:     r.Numbers,
+			Difficulty:  m.Config.Difficulty,
+		}
+		m.test = test.NewWithText(m.Config, m.Styles, tcfg, r.Text)
+		if m.test.Engine.PauseWhenIdle {
+			// the race clock runs on for everyone, so idling cannot stop it
+			m.test.Engine.IdleTimeout = 0
+			m.test.Engine.PauseWhenIdle = false
+		}
+		m.test.Width = m.Width
+		m.test.Height = m.Height
+		m.test.Racers = m.others()
+		m.phase = phaseCountdown
+		m.count = countdown
+		cmds = append(cmds, countdownCmd())
+	case race.TypeUpdate:
+		m.players = msg.Players
+		m.test.Racers = m.others()
+	case race.TypeStandings:
+		m.players = msg.Players
+		m.phase = phaseDone
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// Test returns the player's test, with a nil Engine before the race starts
+func (m Model) Test() test.Model {
+	return m.test
+}
+
+// others returns the players other than this one
+func (m Model) others() []race.Player {
+	others := []race.Player{}
+	for _, p := range m.players {
+		if p.ID != m.Client.ID {
+			others = append(others, p)
+		}
+	}
+	return others
+}
+
+// report sends the player's progress to the host
+func (m Model) report() {
+	e := m.test.Engine
+	elapsed := time.Since(m.begin)
+	if !e.EndTime.IsZero() {
+		elapsed = e.EndTime.Sub(m.begin)
+	}
+	m.Client.Report(race.Player{
+		Progress: e.Progress(),
+		WPM:      e.CurrentNetWPM(),
+		Accuracy: e.CurrentAccuracy(),
+		Elapsed:  elapsed.Seconds(),
+		Finished: e.Finished,
+		Failed:   e.Failed,
+	})
+}
+
+func (m Model) View() string {
+	if m.phase == phaseRacing && m.err == nil {
+		return m.test.View()
+	}
+
+	t := m.Styles.Theme
+	titleStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	errStyle := lipgloss.NewStyle().Foreground(t.Error)
+	var b strings.Builder
+
+	switch {
+	case m.err != nil:
+		b.WriteString(errStyle.Render("race: " + m.err.Error()))
+		b.WriteString("\n\n")
+		b.WriteString(labelStyle.Render("esc quit"))
+
+	case m.phase == phaseLobby:
+		b.WriteString(titleStyle.Render("race lobby"))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderLobby())
+		b.WriteString("\n\n")
+		if m.Server != nil {
+			b.WriteString(labelStyle.Render("others join with"))
+			b.WriteString("\n")
+			for _, addr := range m.Server.JoinAddrs() {
+				b.WriteString(labelStyle.Render("  taps race join " + addr))
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+			b.WriteString(labelStyle.Render("enter start | esc quit"))
+		} else {
+			b.WriteString(labelStyle.Render("waiting for the host to start | esc leave"))
+		}
+
+	case m.phase == phaseCountdown:
+		b.WriteString(labelStyle.Render("race starts in"))
+		b.WriteString("\n\n")
+		b.WriteString(theme.RainbowText(fmt.Sprint(m.count), string(t.Main), string(t.Caret)))
+		b.WriteString("\n\n")
+		b.WriteString(labelStyle.Render(fmt.Sprintf("%d words | %s | %d racers", m.race.WordCount, m.race.Language, len(m.players))))
+
+	case m.phase == phaseDone:
+		b.WriteString(titleStyle.Render("standings"))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderStandings())
+		b.WriteString("\n\n")
+		b.WriteString(labelStyle.Render(fmt.Sprintf("seed %d | enter or esc quit", m.race.Seed)))
+	}
+
+	content := b.String()
+	if m.Width > 0 && m.Height > 0 {
+		content = lipgloss.Place(m.Width, m.Height, lipgloss.Center, lipgloss.Center, content)
+	}
+	return content
+}
+
+// renderLobby lists the players waiting for the race
+func (m Model) renderLobby() string {
+	t := m.Styles.Theme
+	nameStyle := lipgloss.NewStyle().Foreground(t.Foreground)
+	youStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+
+	var lines []string
+	for _, p := range m.players {
+		if p.ID == m.Client.ID {
+			lines = append(lines, youStyle.Render(p.Name+" (you)"))
+		} else {
+			lines = append(lines, nameStyle.Render(p.Name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderStandings ranks the players with their results
+func (m Model) renderStandings() string {
+	t := m.Styles.Theme
+	headerStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	rowStyle := lipgloss.NewStyle().Foreground(t.Foreground)
+	youStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+
+	lines := []string{headerStyle.Render(fmt.Sprintf("%-4s %-16s %7s %7s %8s", "", "name", "wpm", "acc", "time"))}
+	for _, p := range m.players {
+		result := fmt.Sprintf("%7.0f %6.1f%% %7.1fs", p.WPM, p.Accuracy, p.Elapsed)
+		switch {
+		case p.Left:
+			result = fmt.Sprintf("%24s", "left")
+		case p.Failed:
+			result = fmt.Sprintf("%24s", "failed")
+		}
+		// padded by hand, as %-16s counts runes rather than cells
+		name := ansi.Truncate(p.Name, 16, "…")
+		name += strings.Repeat(" ", 16-lipgloss.Width(name))
+		row := fmt.Sprintf("%-4s %s %s", fmt.Sprintf("%d.", p.Place), name, result)
+		if p.ID == m.Client.ID {
+			lines = append(lines, youStyle.Render(row))
+		} else {
+			lines = append(lines, rowStyle.Render(row))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,118 @@
+package results
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/guptarohit/asciigraph"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+// renderGraph plots the net WPM so far, the raw WPM of each second and the
+// burst WPM, marks the seconds with errors beneath the plot and labels the
+// x-axis in seconds
+func (m Model) renderGraph(width int) string {
+	t := m.Styles.Theme
+	e := m.Engine
+	n := len(e.PerSecondWPM)
+
+	rawColor := theme.Blend(t.Sub, t.Foreground, 0.5)
+	sub := asciigraph.AnsiColor(theme.ANSI256(t.Sub))
+	plot := asciigraph.PlotMany([][]float64{fit(e.RawPerSecond(), n), e.BurstPerSecond(), fit(e.PerSecondNet, n)},
+		asciigraph.Width(width),
+		asciigraph.Height(8),
+		asciigraph.Precision(0),
+		asciigraph.SeriesColors(
+			asciigraph.AnsiColor(theme.ANSI256(rawColor)),
+			asciigraph.AnsiColor(theme.ANSI256(t.Caret)),
+			asciigraph.AnsiColor(theme.ANSI256(t.Main)),
+		),
+		asciigraph.AxisColor(sub),
+		asciigraph.LabelColor(sub),
+	)
+	lines := strings.Split(plot, "\n")
+
+	// the plot starts right after the y-axis, found on the first line
+	margin := 0
+	for i, r := range []rune(ansi.Strip(lines[0])) {
+		if r == '┤' || r == '┼' {
+			margin = i + 1
+			break
+		}
+	}
+	column := func(i int) int {
+		return (i*(width-1) + (n-1)/2) / (n - 1)
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	errorStyle := lipgloss.NewStyle().Foreground(t.Error).Bold(true)
+
+	// error markers, with the count where more than one error was made
+	errors := 0
+	marks := []rune(strings.Repeat(" ", width))
+	for i := 0; i < n && i < len(e.ErrorsPerSecond); i++ {
+		c := e.ErrorsPerSecond[i]
+		if c == 0 {
+			continue
+		}
+		errors += c
+		mark := 'x'
+		if c > 1 {
+			mark = rune('0' + min(c, 9))
+		}
+		marks[column(i)] = mark
+	}
+
+	// second labels, spaced so they don't run into each other
+	step := 1
+	for _, s := range []int{1, 2, 5, 10, 15, 30, 60} {
+		step = s
+		if n < 2 || s*(width-1)/(n-1) >= 4 {
+			break
+		}
+	}
+	axis := []rune(strings.Repeat(" ", width+3))
+	for s := step; s <= n; s += step {
+		label := fmt.Sprint(s)
+		col := column(s - 1)
+		if col == 0 && s > 1 {
+			continue
+		}
+		copy(axis[col:], []rune(label))
+	}
+
+	gutter := func(label string) string {
+		return labelStyle.Render(fmt.Sprintf("%*s ", margin-1, label))
+	}
+	lines = append(lines,
+		gutter("err")+errorStyle.Render(string(marks)),
+		gutter("sec")+labelStyle.Render(strings.TrimRight(string(axis), " ")),
+	)
+
+	legend := []string{
+		lipgloss.NewStyle().Foreground(t.Main).Render("── net"),
+		lipgloss.NewStyle().Foreground(rawColor).Render("── raw"),
+		lipgloss.NewStyle().Foreground(t.Caret).Render("── burst"),
+		errorStyle.Render("x") + labelStyle.Render(fmt.Sprintf(" %d errors", errors)),
+	}
+	lines = append(lines, "", strings.Repeat(" ", margin)+strings.Join(legend, "   "))
+
+	graph := strings.Join(lines, "\n")
+	// pad the lines to one width so centering keeps them aligned
+	return lipgloss.NewStyle().Width(lipgloss.Width(graph)).Render(graph)
+}
+
+// fit returns values cut or padded with its last value to n samples
+func fit(values []float64, n int) []float64 {
+	out := make([]float64, n)
+	last := 0.0
+	for i := range out {
+		if i < len(values) {
+			last = values[i]
+		}
+		out[i] = last
+	}
+	return out
+}
@@ -2,11 +2,13 @@ package results
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/guptarohit/asciigraph"
+	"github.com/meszmate/taps/internal/history"
+	"github.com/meszmate/taps/internal/share"
 	"github.com/meszmate/taps/internal/typing"
 	"github.com/meszmate/taps/internal/ui/styles"
 	"github.com/meszmate/taps/internal/ui/theme"
@@ -41,21 +43,34 @@ type Model struct {
 	RawWPM      float64
 	Accuracy    float64
 	Consistency float64
-	Width       int
-	Height      int
+	// PreviousBest is the best earlier result with the same config, if any
+	PreviousBest *history.TestResult
+	Width        int
+	Height       int
+	// showWords swaps the graph for the per-word speed breakdown
+	showWords bool
+	// status reports the outcome of the last save or copy
+	status string
+}
+
+// shareMsg reports a saved share card
+type shareMsg struct {
+	path string
+	err  error
 }
 
 func New(s *styles.Styles, engine *typing.Engine, mode string, tcfg TestConfig) Model {
-	elapsed := engine.ElapsedSeconds()
+	// the engine's clock stopped when the test ended, so these match the
+	// numbers saved to history
 	return Model{
 		Styles:      s,
 		Engine:      engine,
 		Mode:        mode,
 		TCfg:        tcfg,
-		NetWPM:      typing.NetWPM(engine.CorrectChars, elapsed),
-		RawWPM:      typing.RawWPM(engine.TotalTyped, elapsed),
-		Accuracy:    typing.Accuracy(engine.CorrectChars, engine.IncorrectChars, engine.ExtraChars),
-		Consistency: typing.Consistency(engine.PerSecondWPM),
+		NetWPM:      engine.CurrentNetWPM(),
+		RawWPM:      engine.CurrentRawWPM(),
+		Accuracy:    engine.CurrentAccuracy(),
+		Consistency: engine.CurrentConsistency(),
 	}
 }
 
@@ -86,11 +101,36 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			return m, func() tea.Msg { return NewTestMsg{} }
 		case "esc":
 			return m, func() tea.Msg { return BackToMenuMsg{} }
+		case "w":
+			m.showWords = !m.showWords
+		case "s":
+			return m, m.save(share.FormatPNG)
+		case "S":
+			return m, m.save(share.FormatSVG)
+		case "c":
+			if err := share.Copy(os.Stderr, share.Text(m.card())); err != nil {
+				m.status = "copy failed: " + err.Error()
+			} else {
+				m.status = "copied result to clipboard"
+			}
+		}
+
+	case shareMsg:
+		if msg.err != nil {
+			m.status = "save failed: " + msg.err.Error()
+		} else {
+			m.status = "saved " + msg.path
 		}
 	}
 	return m, nil
 }
 
+// IsNewBest reports whether the test beat the previous personal best for
+// its config
+func (m Model) IsNewBest() bool {
+	return m.PreviousBest != nil && !m.Engine.Failed && !m.Engine.AFK && m.NetWPM > m.PreviousBest.NetWPM
+}
+
 func (m Model) View() string {
 	t := m.Styles.Theme
 	var b strings.Builder
@@ -103,6 +143,25 @@ func (m Model) View() string {
 	b.WriteString(labelStyle.Render("wpm"))
 	b.WriteString("\n\n")
 
+	if m.IsNewBest() {
+		b.WriteString(theme.RainbowText("new personal best", string(t.Main), string(t.Caret)))
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Correct).Bold(true).
+			Render(fmt.Sprintf("  +%.2f wpm", m.NetWPM-m.PreviousBest.NetWPM)))
+		b.WriteString(labelStyle.Render(fmt.Sprintf(" over %.2f", m.PreviousBest.NetWPM)))
+		b.WriteString("\n\n")
+	}
+
+	if g := m.Engine.Ghost; g != nil && !m.Engine.Failed {
+		if diff := m.NetWPM - g.WPM; diff >= 0 {
+			b.WriteString(lipgloss.NewStyle().Foreground(t.Correct).
+				Render(fmt.Sprintf("beat the %s ghost by %.2f wpm", g.Label, diff)))
+		} else {
+			b.WriteString(lipgloss.NewStyle().Foreground(t.Error).
+				Render(fmt.Sprintf("the %s ghost won by %.2f wpm", g.Label, -diff)))
+		}
+		b.WriteString("\n\n")
+	}
+
 	// Stats grid
 	statLabel := lipgloss.NewStyle().Foreground(t.Sub)
 	statValue := lipgloss.NewStyle().Foreground(t.Foreground).Bold(true)
@@ -142,27 +201,80 @@ func (m Model) View() string {
 	b.WriteString(charLabel.Render("            correct / incorrect / extra / missed"))
 	b.WriteString("\n\n")
 
-	// WPM graph
-	if len(m.Engine.PerSecondWPM) > 1 {
-		graphWidth := m.Width - 20
-		if graphWidth < 30 {
-			graphWidth = 30
-		}
-		if graphWidth > 80 {
-			graphWidth = 80
-		}
-		graph := asciigraph.Plot(m.Engine.PerSecondWPM,
-			asciigraph.Width(graphWidth),
-			asciigraph.Height(8),
-			asciigraph.Caption("raw wpm over time"),
-		)
-		graphStyle := lipgloss.NewStyle().Foreground(t.Sub)
-		b.WriteString(graphStyle.Render(graph))
+	// WPM graph or word speeds
+	graphWidth := m.Width - 20
+	if graphWidth < 30 {
+		graphWidth = 30
+	}
+	if graphWidth > 80 {
+		graphWidth = 80
+	}
+	if m.showWords {
+		b.WriteString(m.renderWords(graphWidth))
+		b.WriteString("\n\n")
+	} else if len(m.Engine.PerSecondWPM) > 1 {
+		b.WriteString(m.renderGraph(graphWidth))
 		b.WriteString("\n\n")
 	}
 
 	// Test config summary
 	cfgStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	b.WriteString(cfgStyle.Render(m.configLabel()))
+	b.WriteString("\n")
+	b.WriteString(cfgStyle.Render(profileNote(m.Engine.MetricsProfile)))
+	b.WriteString("\n\n")
+
+	// If failed
+	if m.Engine.Failed {
+		failStyle := lipgloss.NewStyle().Foreground(t.Error).Bold(true)
+		b.WriteString(failStyle.Render("Test failed: "+m.Engine.FailedReason))
+		b.WriteString("\n\n")
+	} else if m.Engine.AFK {
+		failStyle := lipgloss.NewStyle().Foreground(t.Error).Bold(true)
+		b.WriteString(failStyle.Render(fmt.Sprintf("Test invalid: afk for %.0fs, not counted in personal bests and averages", m.Engine.IdleSeconds)))
+		b.WriteString("\n\n")
+	} else if m.Engine.IdleSeconds > 0 {
+		pausedStyle := lipgloss.NewStyle().Foreground(t.Sub)
+		b.WriteString(pausedStyle.Render(fmt.Sprintf("paused for %.0fs while afk", m.Engine.IdleSeconds)))
+		b.WriteString("\n\n")
+	}
+	if m.Engine.PausedSeconds > 0 {
+		pausedStyle := lipgloss.NewStyle().Foreground(t.Sub)
+		b.WriteString(pausedStyle.Render(fmt.Sprintf("paused for %.0fs, marked as paused in history", m.Engine.PausedSeconds)))
+		b.WriteString("\n\n")
+	}
+
+	if m.status != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Sub).Render(m.status))
+		b.WriteString("\n\n")
+	}
+
+	// Keybinds
+	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	words := "w word speeds"
+	if m.showWords {
+		words = "w graph"
+	}
+	b.WriteString(helpStyle.Render("tab restart | enter new test | " + words + " | s/S save png/svg | c copy | esc menu"))
+
+	content := b.String()
+	if m.Width > 0 && m.Height > 0 {
+		content = lipgloss.Place(m.Width, m.Height, lipgloss.Center, lipgloss.Center, content)
+	}
+
+	return content
+}
+
+// profileNote explains how the numbers of a metrics profile are defined
+func profileNote(profile string) string {
+	if profile == typing.ProfileMonkeytype {
+		return "monkeytype metrics: wpm counts correctly typed words only, accuracy every keypress including fixed mistakes"
+	}
+	return "taps metrics: wpm counts every correct character, accuracy the final text"
+}
+
+// configLabel summarizes the test config, e.g. "time | 30s | english"
+func (m Model) configLabel() string {
 	cfgParts := []string{m.TCfg.Mode}
 	if m.TCfg.Mode == "time" {
 		cfgParts = append(cfgParts, fmt.Sprintf("%ds", m.TCfg.Duration))
@@ -180,24 +292,30 @@ func (m Model) View() string {
 	if m.TCfg.Difficulty != "normal" {
 		cfgParts = append(cfgParts, m.TCfg.Difficulty)
 	}
-	b.WriteString(cfgStyle.Render(strings.Join(cfgParts, " | ")))
-	b.WriteString("\n\n")
+	return strings.Join(cfgParts, " | ")
+}
 
-	// If failed
-	if m.Engine.Failed {
-		failStyle := lipgloss.NewStyle().Foreground(t.Error).Bold(true)
-		b.WriteString(failStyle.Render("Test failed: "+m.Engine.FailedReason))
-		b.WriteString("\n\n")
+// card builds the share card of the result
+func (m Model) card() share.Card {
+	n := len(m.Engine.PerSecondWPM)
+	return share.Card{
+		NetWPM:      m.NetWPM,
+		RawWPM:      m.RawWPM,
+		Accuracy:    m.Accuracy,
+		Consistency: m.Consistency,
+		Config:      m.configLabel(),
+		Date:        m.Engine.StartTime,
+		Net:         fit(m.Engine.PerSecondNet, n),
+		Raw:         fit(m.Engine.RawPerSecond(), n),
+		Theme:       m.Styles.Theme,
 	}
+}
 
-	// Keybinds
-	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
-	b.WriteString(helpStyle.Render("tab restart | enter new test | esc menu"))
-
-	content := b.String()
-	if m.Width > 0 && m.Height > 0 {
-		content = lipgloss.Place(m.Width, m.Height, lipgloss.Center, lipgloss.Center, content)
+// save writes the share card of the result to the exports directory
+func (m Model) save(format share.Format) tea.Cmd {
+	c := m.card()
+	return func() tea.Msg {
+		p, err := share.Save(c, format)
+		return shareMsg{p, err}
 	}
-
-	return content
 }
@@ -0,0 +1,98 @@
+package results
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxWordRows caps the rows of words shown in the word breakdown
+const maxWordRows = 6
+
+// renderWords lays out the ended words of the test with their burst WPM
+// beneath each one, highlighting the slowest
+func (m Model) renderWords(width int) string {
+	t := m.Styles.Theme
+	labelStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Foreground).Bold(true)
+	wordStyle := lipgloss.NewStyle().Foreground(t.Foreground)
+	slowStyle := lipgloss.NewStyle().Foreground(t.Error).Bold(true)
+
+	e := m.Engine
+	var timed []int
+	for i, wpm := range e.WordBurst {
+		if wpm > 0 {
+			timed = append(timed, i)
+		}
+	}
+	if len(timed) == 0 {
+		return labelStyle.Render("no words were timed in this test")
+	}
+
+	// the slowest tenth of the words, at least one and at most five
+	bySpeed := slices.Clone(timed)
+	slices.SortStableFunc(bySpeed, func(a, b int) int {
+		return cmp.Compare(e.WordBurst[a], e.WordBurst[b])
+	})
+	slow := make(map[int]bool)
+	for _, i := range bySpeed[:min(max(len(bySpeed)/10, 1), 5)] {
+		slow[i] = true
+	}
+
+	var sum float64
+	for _, i := range timed {
+		sum += e.WordBurst[i]
+	}
+	fastest, slowest := bySpeed[len(bySpeed)-1], bySpeed[0]
+
+	var b strings.Builder
+	b.WriteString(labelStyle.Render("burst avg "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%.0f wpm", sum/float64(len(timed)))))
+	b.WriteString(labelStyle.Render("  fastest "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%s %.0f", e.Words[fastest], e.WordBurst[fastest])))
+	b.WriteString(labelStyle.Render("  slowest "))
+	b.WriteString(slowStyle.Render(fmt.Sprintf("%s %.0f", e.Words[slowest], e.WordBurst[slowest])))
+	b.WriteString("\n\n")
+
+	// rows of words, each over its burst WPM
+	var rows []string
+	var words, speeds strings.Builder
+	used := 0
+	flush := func() {
+		rows = append(rows, words.String(), speeds.String())
+		words.Reset()
+		speeds.Reset()
+		used = 0
+	}
+	shown := 0
+	for _, i := range timed {
+		word := e.Words[i]
+		speed := fmt.Sprintf("%.0f", e.WordBurst[i])
+		cell := max(lipgloss.Width(word), len(speed)) + 1
+		if used > 0 && used+cell > width {
+			flush()
+			if len(rows)/2 == maxWordRows {
+				break
+			}
+		}
+		ws, ss := wordStyle, labelStyle
+		if slow[i] {
+			ws, ss = slowStyle, slowStyle
+		}
+		words.WriteString(ws.Render(fmt.Sprintf("%-*s", cell, word)))
+		speeds.WriteString(ss.Render(fmt.Sprintf("%-*s", cell, speed)))
+		used += cell
+		shown++
+	}
+	if used > 0 {
+		flush()
+	}
+	if more := len(timed) - shown; more > 0 {
+		rows = append(rows, labelStyle.Render(fmt.Sprintf("and %d more words", more)))
+	}
+	b.WriteString(lipgloss.JoinVertical(lipgloss.Left, rows...))
+	return b.String()
+}
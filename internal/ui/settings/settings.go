@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/typing"
 	"github.com/meszmate/taps/internal/ui/styles"
 	"github.com/meszmate/taps/internal/ui/theme"
 )
@@ -15,6 +16,7 @@ type BackToMenuMsg struct{}
 type ThemeChangedMsg struct {
 	ThemeName string
 }
+type OpenThemeEditorMsg struct{}
 
 type settingType int
 
@@ -42,11 +44,16 @@ type Model struct {
 }
 
 func New(cfg *config.Config, s *styles.Styles) Model {
+	themeNames := theme.ThemeNames()
+	if cfg.CustomTheme != nil {
+		themeNames = append(themeNames, theme.CustomName)
+	}
+
 	settings := []setting{
 		{
 			label:   "Mode",
 			typ:     settingSelector,
-			options: []string{"time", "words", "quote", "zen"},
+			options: config.Modes,
 			getVal:  func(c *config.Config) string { return c.Mode },
 			setVal:  func(c *config.Config, v string) { c.Mode = v },
 		},
@@ -75,7 +82,7 @@ func New(cfg *config.Config, s *styles.Styles) Model {
 		{
 			label:   "Language",
 			typ:     settingSelector,
-			options: []string{"english", "english_1k"},
+			options: typing.Languages(),
 			getVal:  func(c *config.Config) string { return c.Language },
 			setVal:  func(c *config.Config, v string) { c.Language = v },
 		},
@@ -106,21 +113,21 @@ func New(cfg *config.Config, s *styles.Styles) Model {
 		{
 			label:   "Difficulty",
 			typ:     settingSelector,
-			options: []string{"normal", "expert", "master"},
+			options: config.Difficulties,
 			getVal:  func(c *config.Config) string { return c.Difficulty },
 			setVal:  func(c *config.Config, v string) { c.Difficulty = v },
 		},
 		{
 			label:   "Theme",
 			typ:     settingSelector,
-			options: theme.ThemeNames(),
+			options: themeNames,
 			getVal:  func(c *config.Config) string { return c.Theme },
 			setVal:  func(c *config.Config, v string) { c.Theme = v },
 		},
 		{
 			label:   "Cursor Style",
 			typ:     settingSelector,
-			options: []string{"line", "block", "underline"},
+			options: config.CursorStyles,
 			getVal:  func(c *config.Config) string { return c.CursorStyle },
 			setVal:  func(c *config.Config, v string) { c.CursorStyle = v },
 		},
@@ -151,7 +158,7 @@ func New(cfg *config.Config, s *styles.Styles) Model {
 		{
 			label:   "Stop on Error",
 			typ:     settingSelector,
-			options: []string{"off", "word", "letter"},
+			options: config.StopOnErrorModes,
 			getVal:  func(c *config.Config) string { return c.StopOnError },
 			setVal:  func(c *config.Config, v string) { c.StopOnError = v },
 		},
@@ -218,10 +225,92 @@ func New(cfg *config.Config, s *styles.Styles) Model {
 		{
 			label:   "Quote Length",
 			typ:     settingSelector,
-			options: []string{"short", "medium", "long"},
+			options: config.QuoteLengths,
 			getVal:  func(c *config.Config) string { return c.QuoteLength },
 			setVal:  func(c *config.Config, v string) { c.QuoteLength = v },
 		},
+		{
+			label:   "History Storage",
+			typ:     settingSelector,
+			options: config.HistoryBackends,
+			getVal:  func(c *config.Config) string { return c.HistoryBackend },
+			setVal:  func(c *config.Config, v string) { c.HistoryBackend = v },
+		},
+		{
+			label:   "Metrics",
+			typ:     settingSelector,
+			options: config.MetricsProfiles,
+			getVal:  func(c *config.Config) string { return c.MetricsProfile },
+			setVal:  func(c *config.Config, v string) { c.MetricsProfile = v },
+		},
+		{
+			label:   "Pace Caret",
+			typ:     settingSelector,
+			options: config.PaceCarets,
+			getVal:  func(c *config.Config) string { return c.PaceCaret },
+			setVal:  func(c *config.Config, v string) { c.PaceCaret = v },
+		},
+		{
+			label:   "Pace Caret WPM",
+			typ:     settingSelector,
+			options: []string{"40", "60", "80", "100", "120", "150", "200"},
+			getVal:  func(c *config.Config) string { return fmt.Sprintf("%d", c.PaceCaretWPM) },
+			setVal: func(c *config.Config, v string) {
+				var w int
+				fmt.Sscanf(v, "%d", &w)
+				c.PaceCaretWPM = w
+			},
+		},
+		{
+			label:   "AFK Detection",
+			typ:     settingSelector,
+			options: config.AFKModes,
+			getVal:  func(c *config.Config) string { return c.AFKMode },
+			setVal:  func(c *config.Config, v string) { c.AFKMode = v },
+		},
+		{
+			label:   "AFK Timeout",
+			typ:     settingSelector,
+			options: []string{"5", "10", "15", "30", "60"},
+			getVal:  func(c *config.Config) string { return fmt.Sprintf("%d", c.AFKTimeout) },
+			setVal: func(c *config.Config, v string) {
+				var t int
+				fmt.Sscanf(v, "%d", &t)
+				c.AFKTimeout = t
+			},
+		},
+		{
+			label:   "Daily Minutes",
+			typ:     settingSelector,
+			options: []string{"off", "5", "10", "15", "20", "30", "45", "60"},
+			getVal:  func(c *config.Config) string { return goalString(c.Goals.MinutesPerDay) },
+			setVal:  func(c *config.Config, v string) { c.Goals.MinutesPerDay = goalValue(v) },
+		},
+		{
+			label:   "Daily Tests",
+			typ:     settingSelector,
+			options: []string{"off", "5", "10", "20", "30", "50"},
+			getVal:  func(c *config.Config) string { return goalString(c.Goals.TestsPerDay) },
+			setVal:  func(c *config.Config, v string) { c.Goals.TestsPerDay = goalValue(v) },
+		},
+		{
+			label:   "WPM Goal",
+			typ:     settingSelector,
+			options: []string{"off", "40", "50", "60", "70", "80", "90", "100", "120", "150"},
+			getVal:  func(c *config.Config) string { return goalString(c.Goals.TargetWPM) },
+			setVal:  func(c *config.Config, v string) { c.Goals.TargetWPM = goalValue(v) },
+		},
+		{
+			label:   "WPM Goal Test",
+			typ:     settingSelector,
+			options: []string{"time 15", "time 30", "time 60", "time 120", "words 10", "words 25", "words 50", "words 100"},
+			getVal: func(c *config.Config) string {
+				return fmt.Sprintf("%s %d", c.Goals.TargetMode, c.Goals.TargetLength)
+			},
+			setVal: func(c *config.Config, v string) {
+				fmt.Sscanf(v, "%s %d", &c.Goals.TargetMode, &c.Goals.TargetLength)
+			},
+		},
 	}
 
 	return Model{
@@ -264,8 +353,12 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 					m.scroll = m.cursor - visibleLines + 1
 				}
 			}
+		case "e":
+			_ = m.Config.Save()
+			return m, func() tea.Msg { return OpenThemeEditorMsg{} }
 		case "left", "h":
-			m.cycleSetting(-1)
+			cmd := m.cycleSetting(-1)
+			return m, cmd
 		case "right", "l", "enter":
 			cmd := m.cycleSetting(1)
 			return m, cmd
@@ -347,7 +440,7 @@ func (m Model) View() string {
 
 	b.WriteString("\n")
 	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
-	b.WriteString(helpStyle.Render("arrows navigate | left/right change | esc back"))
+	b.WriteString(helpStyle.Render("arrows navigate | left/right change | e edit theme | esc back"))
 
 	content := b.String()
 	if m.width > 0 && m.height > 0 {
@@ -356,3 +449,17 @@ func (m Model) View() string {
 
 	return content
 }
+
+// goalString shows a goal setting, where zero turns the goal off
+func goalString(v int) string {
+	if v == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+func goalValue(s string) int {
+	var v int
+	fmt.Sscanf(s, "%d", &v)
+	return v
+}
@@ -5,12 +5,14 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/meszmate/taps/internal/config"
+	"github.com/meszmate/taps/internal/race"
 	"github.com/meszmate/taps/internal/typing"
 	"github.com/meszmate/taps/internal/ui/styles"
 )
 
 type TickMsg time.Time
 type WPMSampleMsg time.Time
+type PaceTickMsg time.Time
 
 type TestFinishedMsg struct {
 	Engine *typing.Engine
@@ -37,10 +39,11 @@ type Model struct {
 	Engine  *typing.Engine
 	Mode    string
 	TCfg    TestConfig
-	Timer   int // remaining seconds (time mode)
 	Width   int
 	Height  int
 	started bool
+	// Racers are the other players of a race, shown above the text
+	Racers []race.Player
 }
 
 func New(cfg *config.Config, s *styles.Styles, mode string, duration, wordCount int, quoteLength string) Model {
@@ -70,15 +73,28 @@ func New(cfg *config.Config, s *styles.Styles, mode string, duration, wordCount
 		target = typing.GenerateWords(50, cfg.Language, cfg.Punctuation, cfg.Numbers)
 	}
 
+	return NewWithText(cfg, s, tcfg, target)
+}
+
+// NewWithText sets up a test of the given text, such as a race's
+func NewWithText(cfg *config.Config, s *styles.Styles, tcfg TestConfig, target string) Model {
 	engine := typing.NewEngine(target, cfg.StopOnError, cfg.FreedomMode, cfg.Difficulty)
+	engine.MetricsProfile = cfg.MetricsProfile
+	if tcfg.Mode == "time" {
+		engine.TimeLimit = time.Duration(tcfg.Duration) * time.Second
+	}
+	// zen tests have no end to wait for, so a break cannot spoil them
+	if cfg.AFKMode == "pause" || cfg.AFKMode == "invalid" && tcfg.Mode != "zen" {
+		engine.IdleTimeout = time.Duration(cfg.AFKTimeout) * time.Second
+		engine.PauseWhenIdle = cfg.AFKMode == "pause"
+	}
 
 	return Model{
 		Config: cfg,
 		Styles: s,
 		Engine: engine,
-		Mode:   mode,
+		Mode:   tcfg.Mode,
 		TCfg:   tcfg,
-		Timer:  duration,
 	}
 }
 
@@ -86,8 +102,21 @@ func (m Model) Init() tea.Cmd {
 	return nil
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+// Timer returns the seconds left of a time test, as shown in the countdown
+func (m Model) Timer() int {
+	return int((m.Engine.Remaining() + time.Second - 1) / time.Second)
+}
+
+// tickCmd schedules the next countdown update for when the shown second
+// changes, so the countdown follows the engine's clock without drifting
+func (m Model) tickCmd() tea.Cmd {
+	next := time.Second
+	if !m.Engine.Paused {
+		if r := m.Engine.Remaining() % time.Second; r > 0 {
+			next = r
+		}
+	}
+	return tea.Tick(next, func(t time.Time) tea.Msg {
 		return TickMsg(t)
 	})
 }
@@ -98,6 +127,13 @@ func wpmSampleCmd() tea.Cmd {
 	})
 }
 
+// paceTickCmd schedules the next move of the pace caret
+func paceTickCmd() tea.Cmd {
+	return tea.Tick(50*time.Millisecond, func(t time.Time) tea.Msg {
+		return PaceTickMsg(t)
+	})
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -109,29 +145,42 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			return m, nil
 		}
 		if m.Mode == "time" {
-			m.Timer--
-			if m.Timer <= 0 {
+			if m.Engine.Remaining() <= 0 {
 				m.Engine.Finish()
 				return m, m.finishCmd()
 			}
-			return m, tickCmd()
+			return m, m.tickCmd()
 		}
 
 	case WPMSampleMsg:
 		if m.Engine.Started && !m.Engine.Finished {
+			m.Engine.CheckIdle(time.Time(msg))
 			m.Engine.SampleWPM()
 			return m, wpmSampleCmd()
 		}
 
+	case PaceTickMsg:
+		// redraws with the pace caret moved on
+		if m.Engine.Started && !m.Engine.Finished && !m.Engine.Failed {
+			return m, paceTickCmd()
+		}
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
 			return m, tea.Quit
 		case "esc":
 			return m, func() tea.Msg { return BackToMenuMsg{} }
+		case "ctrl+p":
+			if m.Engine.Held {
+				m.Engine.Resume()
+			} else {
+				m.Engine.Pause()
+			}
 		case "tab":
 			// Quick restart
 			newM := New(m.Config, m.Styles, m.Mode, m.TCfg.Duration, m.TCfg.WordCount, m.TCfg.QuoteLength)
+			newM.Engine.Ghost = m.Engine.Ghost
 			newM.Width = m.Width
 			newM.Height = m.Height
 			return newM, nil
@@ -143,25 +192,25 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			if len(msg.Runes) == 1 {
 				key := msg.Runes[0]
 				wasStarted := m.Engine.Started
+				wasDone := m.Engine.Finished || m.Engine.Failed
 				m.Engine.HandleKey(key)
 
 				// Start timer on first keystroke
 				if !wasStarted && m.Engine.Started {
 					var cmds []tea.Cmd
 					if m.Mode == "time" {
-						cmds = append(cmds, tickCmd())
+						cmds = append(cmds, m.tickCmd())
 					}
 					cmds = append(cmds, wpmSampleCmd())
+					if m.Engine.Ghost != nil {
+						cmds = append(cmds, paceTickCmd())
+					}
 					return m, tea.Batch(cmds...)
 				}
 
-				// Check if finished
-				if m.Engine.Finished {
-					return m, m.finishCmd()
-				}
-
-				// Check if failed (expert/master)
-				if m.Engine.Failed {
+				// Check if this key finished or failed (expert/master) the
+				// test, so keys pressed afterwards don't finish it again
+				if !wasDone && (m.Engine.Finished || m.Engine.Failed) {
 					return m, m.finishCmd()
 				}
 			}
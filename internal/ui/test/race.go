@@ -0,0 +1,62 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/meszmate/taps/internal/race"
+)
+
+// renderRacers draws a progress bar with the WPM of the player and each of
+// the other racers
+func (m Model) renderRacers(width int) string {
+	t := m.Styles.Theme
+	nameStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	youStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	emptyStyle := lipgloss.NewStyle().Foreground(t.Sub)
+
+	you := race.Player{
+		Name:     "you",
+		Progress: m.Engine.Progress(),
+		WPM:      m.Engine.CurrentNetWPM(),
+		Finished: m.Engine.Finished,
+		Failed:   m.Engine.Failed,
+	}
+	players := append([]race.Player{you}, m.Racers...)
+
+	nameWidth := 0
+	for _, p := range players {
+		nameWidth = max(nameWidth, lipgloss.Width(p.Name))
+	}
+	nameWidth = min(nameWidth, 16)
+	barWidth := max(width-nameWidth-12, 10)
+
+	var lines []string
+	for i, p := range players {
+		// names are measured in cells, as wide characters take two
+		name := ansi.Truncate(p.Name, nameWidth, "…")
+		name += strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+		ns, barStyle := nameStyle, lipgloss.NewStyle().Foreground(t.Caret)
+		if i == 0 {
+			ns, barStyle = youStyle, youStyle
+		}
+		filled := int(p.Progress * float64(barWidth))
+
+		status := fmt.Sprintf("%3.0f wpm", p.WPM)
+		switch {
+		case p.Left:
+			status = "left"
+		case p.Failed:
+			status = "failed"
+		case p.Finished:
+			status += " ✓"
+		}
+		lines = append(lines, ns.Render(name+" ")+
+			barStyle.Render(strings.Repeat("█", filled))+
+			emptyStyle.Render(strings.Repeat("░", barWidth-filled))+
+			nameStyle.Render(" "+status))
+	}
+	return strings.Join(lines, "\n")
+}
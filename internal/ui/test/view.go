@@ -6,6 +6,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/meszmate/taps/internal/typing"
+	"github.com/meszmate/taps/internal/ui/theme"
 )
 
 func (m Model) View() string {
@@ -30,14 +31,28 @@ func (m Model) View() string {
 		b.WriteString("\n\n")
 	}
 
+	// Other racers
+	if m.Racers != nil {
+		b.WriteString(m.renderRacers(textWidth))
+		b.WriteString("\n\n")
+	}
+
 	// Render typed text
+	var text string
 	if m.Config.TapeMode {
-		b.WriteString(m.renderTapeMode(textWidth))
+		text = m.renderTapeMode(textWidth)
 	} else if m.Config.ShowAllLines {
-		b.WriteString(m.renderAllLines(textWidth))
+		text = m.renderAllLines(textWidth)
 	} else {
-		b.WriteString(m.render3Lines(textWidth))
+		text = m.render3Lines(textWidth)
 	}
+	if m.Engine.Held {
+		// hide the text while paused, keeping its place on screen
+		pausedStyle := lipgloss.NewStyle().Foreground(t.Sub).Bold(true)
+		text = lipgloss.Place(lipgloss.Width(text), lipgloss.Height(text), lipgloss.Center, lipgloss.Center,
+			pausedStyle.Render("paused, ctrl+p to resume"))
+	}
+	b.WriteString(text)
 
 	// Failed message
 	if m.Engine.Failed {
@@ -46,11 +61,32 @@ func (m Model) View() string {
 		b.WriteString(errStyle.Render(m.Engine.FailedReason))
 	}
 
+	// AFK notices
+	if m.Engine.Paused && !m.Engine.Held {
+		b.WriteString("\n\n")
+		pausedStyle := lipgloss.NewStyle().Foreground(t.Sub).Bold(true)
+		b.WriteString(pausedStyle.Render("paused while afk, type to resume"))
+	} else if m.Engine.AFK {
+		b.WriteString("\n\n")
+		errStyle := lipgloss.NewStyle().Foreground(t.Error)
+		b.WriteString(errStyle.Render("afk detected, this test won't count"))
+	}
+
+	if m.Racers != nil && (m.Engine.Finished || m.Engine.Failed) {
+		b.WriteString("\n\n")
+		waitStyle := lipgloss.NewStyle().Foreground(t.Sub).Bold(true)
+		b.WriteString(waitStyle.Render("waiting for the other racers to finish"))
+	}
+
 	// Bottom help
 	if !m.Config.FocusMode {
 		b.WriteString("\n\n")
 		helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
-		b.WriteString(helpStyle.Render("tab restart | esc menu"))
+		help := "tab restart | ctrl+p pause | esc menu"
+		if m.Racers != nil {
+			help = "esc leave the race"
+		}
+		b.WriteString(helpStyle.Render(help))
 	}
 
 	content := b.String()
@@ -69,7 +105,7 @@ func (m Model) renderTopBar() string {
 	switch m.Mode {
 	case "time":
 		timerStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
-		parts = append(parts, timerStyle.Render(fmt.Sprintf("%ds", m.Timer)))
+		parts = append(parts, timerStyle.Render(fmt.Sprintf("%ds", m.Timer())))
 	case "words":
 		typed, total := m.Engine.WordProgress()
 		progressStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
@@ -104,6 +140,7 @@ func (m Model) renderTopBar() string {
 func (m Model) renderChars(startIdx, endIdx int) string {
 	t := m.Styles.Theme
 	var b strings.Builder
+	ghostPos := m.Engine.GhostPos()
 
 	for i := startIdx; i < endIdx && i < len(m.Engine.Chars); i++ {
 		ch := m.Engine.Chars[i]
@@ -113,6 +150,10 @@ func (m Model) renderChars(startIdx, endIdx int) string {
 			b.WriteString(m.renderCursor(ch))
 			continue
 		}
+		if i == ghostPos {
+			b.WriteString(m.renderGhost(ch))
+			continue
+		}
 
 		var style lipgloss.Style
 		switch ch.State {
@@ -183,6 +224,28 @@ func (m Model) renderCursor(ch typing.DisplayChar) string {
 	}
 }
 
+// renderGhost renders the pace caret in a faded caret color. It never adds
+// a column, so the text doesn't shift as it moves.
+func (m Model) renderGhost(ch typing.DisplayChar) string {
+	t := m.Styles.Theme
+	ghost := theme.Blend(t.Caret, t.Background, 0.5)
+	r := ch.Expected
+	if ch.State == typing.CharIncorrect && ch.Typed != 0 {
+		r = ch.Typed
+	}
+
+	if m.Config.CursorStyle == "block" {
+		return lipgloss.NewStyle().
+			Background(ghost).
+			Foreground(t.Background).
+			Render(string(r))
+	}
+	return lipgloss.NewStyle().
+		Foreground(ghost).
+		Underline(true).
+		Render(string(r))
+}
+
 // wordWrapIndices splits chars into lines by word boundaries within maxWidth
 func (m Model) wordWrapLines(maxWidth int) []struct{ start, end int } {
 	var lines []struct{ start, end int }
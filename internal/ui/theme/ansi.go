@@ -0,0 +1,36 @@
+package theme
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// cubeLevels are the channel values of the 6x6x6 color cube of the xterm
+// 256 color palette
+var cubeLevels = [6]float64{0, 95, 135, 175, 215, 255}
+
+// ANSI256 returns the xterm 256 color palette index closest to a hex color,
+// for libraries such as asciigraph that only accept palette colors. The 16
+// system colors are skipped since terminals customize them.
+func ANSI256(c lipgloss.Color) uint8 {
+	col, err := colorful.Hex(string(c))
+	if err != nil {
+		return 7
+	}
+
+	best, bestDist := uint8(16), -1.0
+	try := func(idx int, r, g, b float64) {
+		d := col.DistanceLab(colorful.Color{R: r / 255, G: g / 255, B: b / 255})
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = uint8(idx), d
+		}
+	}
+	for i := 0; i < 216; i++ {
+		try(16+i, cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6])
+	}
+	for i := 0; i < 24; i++ {
+		v := float64(8 + 10*i)
+		try(232+i, v, v, v)
+	}
+	return best
+}
@@ -0,0 +1,30 @@
+package theme
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// MinContrast is the WCAG ratio below which two colors are considered hard
+// to tell apart for UI elements
+const MinContrast = 3.0
+
+// ContrastRatio returns the WCAG contrast ratio between two hex colors,
+// ranging from 1 (identical) to 21 (black on white)
+func ContrastRatio(a, b lipgloss.Color) float64 {
+	la := relativeLuminance(a)
+	lb := relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func relativeLuminance(c lipgloss.Color) float64 {
+	col, err := colorful.Hex(string(c))
+	if err != nil {
+		return 0
+	}
+	r, g, b := col.LinearRgb()
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
@@ -55,3 +55,13 @@ func FullRainbow(text string) string {
 	}
 	return result
 }
+
+// Blend returns the color t of the way from a to b
+func Blend(a, b lipgloss.Color, t float64) lipgloss.Color {
+	ca, errA := colorful.Hex(string(a))
+	cb, errB := colorful.Hex(string(b))
+	if errA != nil || errB != nil {
+		return b
+	}
+	return lipgloss.Color(ca.BlendLab(cb, t).Clamped().Hex())
+}
@@ -2,6 +2,9 @@ package theme
 
 import "github.com/charmbracelet/lipgloss"
 
+// CustomName is the config theme name that selects the user's custom theme
+const CustomName = "custom"
+
 type Theme struct {
 	Name       string
 	Background lipgloss.Color
@@ -0,0 +1,381 @@
+package themeeditor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guptarohit/asciigraph"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/meszmate/taps/internal/ui/styles"
+	"github.com/meszmate/taps/internal/ui/theme"
+)
+
+type BackMsg struct{}
+type SavedMsg struct {
+	Theme *theme.Theme
+}
+
+type role struct {
+	label string
+	color func(t *theme.Theme) *lipgloss.Color
+}
+
+var roles = []role{
+	{"background", func(t *theme.Theme) *lipgloss.Color { return &t.Background }},
+	{"foreground", func(t *theme.Theme) *lipgloss.Color { return &t.Foreground }},
+	{"sub", func(t *theme.Theme) *lipgloss.Color { return &t.Sub }},
+	{"main", func(t *theme.Theme) *lipgloss.Color { return &t.Main }},
+	{"caret", func(t *theme.Theme) *lipgloss.Color { return &t.Caret }},
+	{"correct", func(t *theme.Theme) *lipgloss.Color { return &t.Correct }},
+	{"error", func(t *theme.Theme) *lipgloss.Color { return &t.Error }},
+	{"extra error", func(t *theme.Theme) *lipgloss.Color { return &t.ExtraError }},
+}
+
+type channel int
+
+const (
+	channelHue channel = iota
+	channelSat
+	channelLight
+)
+
+var channelLabels = []string{"hue", "sat", "light"}
+
+const (
+	sliderWidth  = 24
+	previewWidth = 44
+)
+
+// previewSamples are the WPM samples plotted in the preview graph
+var previewSamples = []float64{62, 71, 78, 75, 82, 86, 84, 90, 87, 85, 91, 88}
+
+type Model struct {
+	Styles  *styles.Styles
+	Theme   *theme.Theme // theme being edited
+	cursor  int
+	channel channel
+	editing bool
+	input   string
+	errMsg  string
+	width   int
+	height  int
+}
+
+func New(s *styles.Styles, base *theme.Theme) Model {
+	t := *base
+	t.Name = "Custom"
+	return Model{
+		Styles: s,
+		Theme:  &t,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		if m.editing {
+			m.updateInput(msg)
+			return m, nil
+		}
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc", "q":
+			return m, func() tea.Msg { return BackMsg{} }
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(roles)-1 {
+				m.cursor++
+			}
+		case "tab":
+			m.channel = (m.channel + 1) % channel(len(channelLabels))
+		case "shift+tab":
+			m.channel = (m.channel + channel(len(channelLabels)) - 1) % channel(len(channelLabels))
+		case "left", "h":
+			m.adjust(-1)
+		case "right", "l":
+			m.adjust(1)
+		case "H":
+			m.adjust(-5)
+		case "L":
+			m.adjust(5)
+		case "#", "enter":
+			m.editing = true
+			m.input = ""
+			m.errMsg = ""
+		case "ctrl+s":
+			saved := *m.Theme
+			return m, func() tea.Msg { return SavedMsg{Theme: &saved} }
+		}
+	}
+	return m, nil
+}
+
+func (m *Model) updateInput(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc":
+		m.editing = false
+		m.errMsg = ""
+	case "enter":
+		hex := "#" + m.input
+		if len(m.input) == 3 {
+			hex = "#" + strings.Repeat(m.input[:1], 2) + strings.Repeat(m.input[1:2], 2) + strings.Repeat(m.input[2:], 2)
+		}
+		col, err := colorful.Hex(hex)
+		if err != nil {
+			m.errMsg = fmt.Sprintf("invalid hex color %q", "#"+m.input)
+			return
+		}
+		*roles[m.cursor].color(m.Theme) = lipgloss.Color(col.Hex())
+		m.editing = false
+		m.errMsg = ""
+	case "backspace", "ctrl+h":
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	default:
+		for _, r := range msg.Runes {
+			if len(m.input) < 6 && strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				m.input += strings.ToLower(string(r))
+			}
+		}
+	}
+}
+
+// adjust moves the selected role's color along the active HSL channel
+func (m *Model) adjust(steps float64) {
+	c := roles[m.cursor].color(m.Theme)
+	h, s, l := hsl(*c)
+	switch m.channel {
+	case channelHue:
+		h = math.Mod(h+steps*5+360, 360)
+	case channelSat:
+		s = clamp01(s + steps*0.02)
+	case channelLight:
+		l = clamp01(l + steps*0.02)
+	}
+	*c = lipgloss.Color(colorful.Hsl(h, s, l).Clamped().Hex())
+}
+
+func hsl(c lipgloss.Color) (h, s, l float64) {
+	col, err := colorful.Hex(string(c))
+	if err != nil {
+		return 0, 0, 0
+	}
+	return col.Hsl()
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func (m Model) View() string {
+	t := m.Styles.Theme
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Foreground(t.Main).Bold(true)
+	b.WriteString(titleStyle.Render("Theme Editor"))
+	b.WriteString("\n\n")
+
+	editor := m.renderRoles() + "\n\n" + m.renderSliders()
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, editor, "    ", m.renderPreview()))
+	b.WriteString("\n\n")
+
+	if m.editing {
+		inputStyle := lipgloss.NewStyle().Foreground(t.Foreground)
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Sub).Render("hex "))
+		b.WriteString(inputStyle.Render("#" + m.input + "_"))
+		b.WriteString("\n")
+	}
+	if m.errMsg != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Error).Render(m.errMsg))
+		b.WriteString("\n")
+	}
+
+	warnStyle := lipgloss.NewStyle().Foreground(t.Error)
+	for _, w := range contrastWarnings(m.Theme) {
+		b.WriteString(warnStyle.Render("warning: " + w))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	helpStyle := lipgloss.NewStyle().Foreground(t.Sub)
+	if m.editing {
+		b.WriteString(helpStyle.Render("type hex digits | enter apply | esc cancel"))
+	} else {
+		b.WriteString(helpStyle.Render("up/down role | tab channel | left/right adjust (H/L x5) | # hex | ctrl+s save | esc back"))
+	}
+
+	content := b.String()
+	if m.width > 0 && m.height > 0 {
+		content = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+	}
+
+	return content
+}
+
+func (m Model) renderRoles() string {
+	t := m.Styles.Theme
+	var b strings.Builder
+
+	for i, r := range roles {
+		c := *r.color(m.Theme)
+		labelStyle := lipgloss.NewStyle().Foreground(t.Sub).Width(12)
+		cursor := "  "
+		if i == m.cursor {
+			labelStyle = lipgloss.NewStyle().Foreground(t.Main).Width(12).Bold(true)
+			cursor = "> "
+		}
+		b.WriteString(cursor)
+		b.WriteString(labelStyle.Render(r.label))
+		b.WriteString(lipgloss.NewStyle().Background(c).Render("    "))
+		b.WriteString(" ")
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Foreground).Render(string(c)))
+		if i < len(roles)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func (m Model) renderSliders() string {
+	t := m.Styles.Theme
+	h, s, l := hsl(*roles[m.cursor].color(m.Theme))
+	values := []float64{h / 360, s, l}
+	readouts := []string{
+		fmt.Sprintf("%3.0f°", h),
+		fmt.Sprintf("%3.0f%%", s*100),
+		fmt.Sprintf("%3.0f%%", l*100),
+	}
+
+	var b strings.Builder
+	for ch := range channelLabels {
+		labelStyle := lipgloss.NewStyle().Foreground(t.Sub).Width(8)
+		if channel(ch) == m.channel {
+			labelStyle = lipgloss.NewStyle().Foreground(t.Main).Width(8).Bold(true)
+		}
+		b.WriteString("  ")
+		b.WriteString(labelStyle.Render(channelLabels[ch]))
+
+		marker := int(math.Round(values[ch] * float64(sliderWidth-1)))
+		for i := 0; i < sliderWidth; i++ {
+			pos := float64(i) / float64(sliderWidth-1)
+			var col colorful.Color
+			switch channel(ch) {
+			case channelHue:
+				col = colorful.Hsl(pos*360, math.Max(s, 0.5), 0.5)
+			case channelSat:
+				col = colorful.Hsl(h, pos, math.Max(math.Min(l, 0.8), 0.2))
+			case channelLight:
+				col = colorful.Hsl(h, s, pos)
+			}
+			cell := lipgloss.NewStyle().Foreground(lipgloss.Color(col.Clamped().Hex()))
+			if i == marker {
+				b.WriteString(lipgloss.NewStyle().Foreground(t.Foreground).Bold(true).Render("┃"))
+			} else {
+				b.WriteString(cell.Render("━"))
+			}
+		}
+		b.WriteString(" ")
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Foreground).Render(readouts[ch]))
+		if ch < len(channelLabels)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// renderPreview draws a miniature test and results screen in the edited theme
+func (m Model) renderPreview() string {
+	t := m.Theme
+	bg := lipgloss.NewStyle().Background(t.Background)
+	fg := func(c lipgloss.Color) lipgloss.Style { return bg.Foreground(c) }
+
+	var lines []string
+	lines = append(lines, "")
+
+	// Top bar as shown during a test
+	lines = append(lines,
+		fg(t.Main).Bold(true).Render("24s")+
+			fg(t.Sub).Render("  87 wpm  96% acc"))
+	lines = append(lines, "")
+
+	// Typing line covering every character state
+	typed := fg(t.Correct).Render("the quick ") +
+		fg(t.Error).Render("v") +
+		fg(t.Correct).Render("rown") +
+		fg(t.ExtraError).Render("n") +
+		fg(t.Correct).Render(" fox ") +
+		fg(t.Caret).Render("|j") +
+		fg(t.Sub).Render("umps over")
+	lines = append(lines, typed)
+	lines = append(lines, fg(t.Sub).Render("the lazy dog and ")+fg(t.Sub).Strikethrough(true).Render("runs")+fg(t.Sub).Render(" away"))
+	lines = append(lines, "")
+
+	// Results summary
+	lines = append(lines,
+		fg(t.Main).Bold(true).Render("87")+
+			fg(t.Sub).Render(" wpm  ")+
+			fg(t.Sub).Render("acc ")+
+			fg(t.Foreground).Bold(true).Render("96.2%"))
+	lines = append(lines, "")
+
+	graph := asciigraph.Plot(previewSamples,
+		asciigraph.Width(previewWidth-14),
+		asciigraph.Height(5),
+		asciigraph.Precision(0),
+	)
+	for _, gl := range strings.Split(graph, "\n") {
+		lines = append(lines, fg(t.Sub).Render(gl))
+	}
+	lines = append(lines, "")
+
+	var b strings.Builder
+	for i, line := range lines {
+		pad := previewWidth - 2 - lipgloss.Width(line)
+		if pad < 0 {
+			pad = 0
+		}
+		b.WriteString(bg.Render("  "))
+		b.WriteString(line)
+		b.WriteString(bg.Render(strings.Repeat(" ", pad)))
+		if i < len(lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// contrastWarnings reports feedback colors that are hard to see on the background
+func contrastWarnings(t *theme.Theme) []string {
+	var warnings []string
+	checks := []struct {
+		label string
+		color lipgloss.Color
+	}{
+		{"correct", t.Correct},
+		{"error", t.Error},
+	}
+	for _, c := range checks {
+		ratio := theme.ContrastRatio(c.color, t.Background)
+		if ratio < theme.MinContrast {
+			warnings = append(warnings, fmt.Sprintf("%s color is hard to distinguish from the background (%.1f:1, want %.1f:1)",
+				c.label, ratio, theme.MinContrast))
+		}
+	}
+	return warnings
+}